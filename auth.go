@@ -0,0 +1,239 @@
+package httpdir
+
+import (
+	"bufio"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Access specifies the authentication required to access a directory, and
+// its descendants, when served using the Handler method of Dir.
+type Access struct {
+	// Realm is sent to the client in the WWW-Authenticate header.
+	Realm string
+
+	// Basic, if non-nil, is used to verify credentials supplied with HTTP
+	// Basic authentication.
+	Basic func(username, password string) bool
+
+	// Bearer, if non-nil, is used to verify tokens supplied with HTTP
+	// Bearer authentication.
+	Bearer func(token string) bool
+}
+
+func (a *Access) allowed(r *http.Request) bool {
+	if a.Basic != nil {
+		if username, password, ok := r.BasicAuth(); ok {
+			return a.Basic(username, password)
+		}
+	}
+
+	if a.Bearer != nil {
+		const prefix = "Bearer "
+
+		if auth := r.Header.Get("Authorization"); len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+			return a.Bearer(auth[len(prefix):])
+		}
+	}
+
+	return false
+}
+
+func (a *Access) challenge(w http.ResponseWriter) {
+	realm := strconv.Quote(a.Realm)
+
+	if a.Basic != nil {
+		w.Header().Add("WWW-Authenticate", "Basic realm="+realm+", charset=\"UTF-8\"")
+	}
+
+	if a.Bearer != nil {
+		w.Header().Add("WWW-Authenticate", "Bearer realm="+realm)
+	}
+
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// SetAccess is a convenience function for Default.SetAccess.
+func SetAccess(name string, a *Access) error {
+	return Default.SetAccess(name, a)
+}
+
+// SetAccess sets the authentication required to access the named directory,
+// and all of its descendants, when served using the Handler method.
+//
+// The Access of a directory overrides that of its ancestors. A nil Access
+// removes any authentication set on the directory.
+//
+// NB: The Access is not checked when using the Open method directly.
+func (d Dir) SetAccess(name string, a *Access) error {
+	parts, err := splitPath("setaccess", name, false)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := d.getPath("setaccess", parts)
+	if err != nil {
+		return err
+	}
+
+	dirs[len(dirs)-1].access = a
+
+	return nil
+}
+
+// access returns the Access that applies to the named path, which is cleaned
+// in the same way as by http.FileServer. The returned bool is false if the
+// path cannot be resolved, in which case the request must be refused.
+func (d Dir) access(name string) (*Access, bool) {
+	parts, err := splitPath("open", path.Clean("/"+name), false)
+	if err != nil {
+		return nil, false
+	}
+
+	dn := d.d
+	a := dn.access
+
+	for _, part := range parts {
+		_, n, ok := dn.lookup(part)
+		if !ok {
+			break
+		}
+
+		if dn, ok = n.(*dir); !ok {
+			break
+		}
+
+		if dn.access != nil {
+			a = dn.access
+		}
+	}
+
+	return a, true
+}
+
+// Htpasswd reads htpasswd-style credentials from the named node, returning a
+// function suitable for use as the Basic field of Access.
+//
+// Each line of the node should be of the form "username:hash", where hash is
+// a bcrypt hash of the password. Blank lines and lines beginning with a '#'
+// are ignored.
+//
+// The credentials are read once, so later changes to the node are not
+// reflected in the returned function. As the node is part of the tree, you may
+// wish to use SetRules to prevent it from being served; the node will still be
+// read by this method.
+//
+// Passwords for unknown usernames are compared against a dummy hash, of the
+// same cost as the most costly in the node, so that the time taken does not
+// reveal which usernames exist.
+func (d Dir) Htpasswd(name string) (func(username, password string) bool, error) {
+	parts, err := splitPath("htpasswd", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := d.getPath("htpasswd", parts[:len(parts)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	_, n, ok := dirs[len(dirs)-1].lookup(parts[len(parts)-1])
+	if !ok {
+		return nil, pathError("htpasswd", parts, fs.ErrNotExist)
+	}
+
+	f, err := n.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "htpasswd", Path: name, Err: err}
+	}
+	defer f.Close()
+
+	users := make(map[string][]byte)
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		username, hash, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, &fs.PathError{Op: "htpasswd", Path: name, Err: fs.ErrInvalid}
+		}
+
+		users[username] = []byte(hash)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, &fs.PathError{Op: "htpasswd", Path: name, Err: err}
+	}
+
+	dummy, err := dummyHash(users)
+	if err != nil {
+		return nil, &fs.PathError{Op: "htpasswd", Path: name, Err: err}
+	}
+
+	return func(username, password string) bool {
+		hash, ok := users[username]
+		if !ok {
+			hash = dummy
+		}
+
+		return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil && ok
+	}, nil
+}
+
+// dummyHash returns a hash, at the highest cost of those given, against which
+// passwords for unknown users are compared, so that the time taken does not
+// reveal which usernames exist.
+func dummyHash(users map[string][]byte) ([]byte, error) {
+	cost := bcrypt.MinCost
+
+	for _, hash := range users {
+		if c, err := bcrypt.Cost(hash); err == nil && c > cost {
+			cost = c
+		}
+	}
+
+	return bcrypt.GenerateFromPassword(nil, cost)
+}
+
+type handler struct {
+	d  Dir
+	fs http.Handler
+}
+
+// Handler returns an http.Handler that serves the Dir using http.FileServer,
+// first checking any Access set with SetAccess.
+//
+// Requests without valid credentials receive a 401 Unauthorized response,
+// with the appropriate WWW-Authenticate headers, before any node is opened.
+// Requests for paths that cannot be resolved receive a 400 Bad Request
+// response.
+func (d Dir) Handler() http.Handler {
+	return handler{
+		d:  d,
+		fs: http.FileServer(d),
+	}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.d.access(r.URL.Path)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+
+		return
+	} else if a != nil && !a.allowed(r) {
+		a.challenge(w)
+
+		return
+	}
+
+	h.fs.ServeHTTP(w, r)
+}
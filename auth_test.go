@@ -0,0 +1,139 @@
+package httpdir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAccess(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	d := New(time.Now())
+
+	for name, data := range map[string]string{
+		"/public/file":        "public",
+		"/internal/file":      "internal",
+		"/internal/.htpasswd": "# users\n\nalice:" + string(hash) + "\n",
+		"/internal/api/file":  "api",
+	} {
+		if err := d.Create(name, FileString(data, time.Now())); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := d.SetRules("/internal", Rule{Pattern: ".htpasswd", Policy: PolicyDeny}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	basic, err := d.Htpasswd("/internal/.htpasswd")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.SetAccess("/internal", &Access{Realm: "Internal", Basic: basic}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.SetAccess("/internal/api", &Access{Realm: "API", Bearer: func(token string) bool { return token == "t0k3n" }}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	h := d.Handler()
+
+	for n, test := range [...]struct {
+		Path, User, Pass, Token string
+		Code                    int
+		Challenge               string
+	}{
+		{Path: "/public/file", Code: http.StatusOK},
+		{Path: "/internal/file", Code: http.StatusUnauthorized, Challenge: "Basic realm=\"Internal\", charset=\"UTF-8\""},
+		{Path: "/internal/missing", Code: http.StatusUnauthorized, Challenge: "Basic realm=\"Internal\", charset=\"UTF-8\""},
+		{Path: "/internal/file", User: "alice", Pass: "wrong", Code: http.StatusUnauthorized},
+		{Path: "/internal/file", User: "bob", Pass: "secret", Code: http.StatusUnauthorized},
+		{Path: "/internal/file", User: "alice", Pass: "secret", Code: http.StatusOK},
+		{Path: "/internal/.htpasswd", User: "alice", Pass: "secret", Code: http.StatusForbidden},
+		{Path: "/internal/api/file", User: "alice", Pass: "secret", Code: http.StatusUnauthorized, Challenge: "Bearer realm=\"API\""},
+		{Path: "/internal/api/file", Token: "wrong", Code: http.StatusUnauthorized},
+		{Path: "/internal/api/file", Token: "t0k3n", Code: http.StatusOK},
+		{Path: "/internal/../internal/file", Code: http.StatusUnauthorized, Challenge: "Basic realm=\"Internal\", charset=\"UTF-8\""},
+		{Path: "/public/../internal/api/./file", Code: http.StatusUnauthorized, Challenge: "Bearer realm=\"API\""},
+		{Path: "/public/../internal/file", User: "alice", Pass: "secret", Code: http.StatusOK},
+		{Path: "//internal//file", Code: http.StatusUnauthorized},
+		{Path: "/../internal/file", Code: http.StatusUnauthorized},
+		{Path: "/internal/%00", Code: http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodGet, test.Path, nil)
+
+		if test.User != "" {
+			r.SetBasicAuth(test.User, test.Pass)
+		} else if test.Token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.Token)
+		}
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if w.Code != test.Code {
+			t.Errorf("test %d: expecting code %d, got %d", n+1, test.Code, w.Code)
+		} else if challenge := w.Header().Get("WWW-Authenticate"); test.Challenge != "" && challenge != test.Challenge {
+			t.Errorf("test %d: expecting challenge %q, got %q", n+1, test.Challenge, challenge)
+		}
+	}
+}
+
+func TestHtpasswdUnknownUser(t *testing.T) {
+	low, err := bcrypt.GenerateFromPassword([]byte("a"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	high, err := bcrypt.GenerateFromPassword([]byte("b"), bcrypt.MinCost+2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	dummy, err := dummyHash(map[string][]byte{"a": low, "b": high})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if cost, err := bcrypt.Cost(dummy); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if cost != bcrypt.MinCost+2 {
+		t.Errorf("expecting dummy hash cost %d, got %d", bcrypt.MinCost+2, cost)
+	}
+
+	d := New(time.Now())
+
+	if err := d.Create("/.htpasswd", FileString("a:"+string(low)+"\n", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	basic, err := d.Htpasswd("/.htpasswd")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, test := range [...]struct {
+		User, Pass string
+		Allowed    bool
+	}{
+		{User: "a", Pass: "a", Allowed: true},
+		{User: "a", Pass: "b"},
+		{User: "b", Pass: "b"},
+		{User: "b", Pass: ""},
+		{User: "", Pass: ""},
+	} {
+		if allowed := basic(test.User, test.Pass); allowed != test.Allowed {
+			t.Errorf("user %q, password %q: expecting allowed %v, got %v", test.User, test.Pass, test.Allowed, allowed)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+func encodePureGoBrotli(w io.Writer, data []byte) {
+	br := brotli.NewWriterLevel(w, brotli.BestCompression)
+	br.Write(data)
+	br.Close()
+}
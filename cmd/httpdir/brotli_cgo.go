@@ -0,0 +1,21 @@
+//go:build cgo
+
+package main
+
+import (
+	"io"
+
+	"github.com/google/brotli/go/cbrotli"
+)
+
+func encodeBrotli(w io.Writer, data []byte, pureGo bool) {
+	if pureGo {
+		encodePureGoBrotli(w, data)
+
+		return
+	}
+
+	br := cbrotli.NewWriter(w, cbrotli.WriterOptions{Quality: 11})
+	br.Write(data)
+	br.Close()
+}
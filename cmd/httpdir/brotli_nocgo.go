@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package main
+
+import "io"
+
+func encodeBrotli(w io.Writer, data []byte, _ bool) {
+	encodePureGoBrotli(w, data)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	maxDiffLines = 10
+	maxDiffWidth = 100
+)
+
+func checkFile(w io.Writer, filename string, generated []byte) bool {
+	if filename == "" || filename == "-" {
+		e(errors.New("check mode requires an output filename"))
+	}
+
+	existing, err := os.ReadFile(filename)
+	e(err)
+
+	if bytes.Equal(existing, generated) {
+		return false
+	}
+
+	oldLines := bytes.Split(existing, []byte{'\n'})
+	newLines := bytes.Split(generated, []byte{'\n'})
+
+	start := 0
+	for start < len(oldLines) && start < len(newLines) && bytes.Equal(oldLines[start], newLines[start]) {
+		start++
+	}
+
+	oldEnd, newEnd := len(oldLines), len(newLines)
+	for oldEnd > start && newEnd > start && bytes.Equal(oldLines[oldEnd-1], newLines[newEnd-1]) {
+		oldEnd--
+		newEnd--
+	}
+
+	switch {
+	case oldEnd == start:
+		fmt.Fprintf(w, "%s is out of date: %s of generated output inserted at line %d of existing file\n", filename, lineRange(start, newEnd), start+1)
+	case newEnd == start:
+		fmt.Fprintf(w, "%s is out of date: %s of existing file removed at line %d of generated output\n", filename, lineRange(start, oldEnd), start+1)
+	default:
+		fmt.Fprintf(w, "%s is out of date: %s of existing file differ from %s of generated output\n", filename, lineRange(start, oldEnd), lineRange(start, newEnd))
+	}
+
+	printDiffLines(w, '-', oldLines[start:oldEnd])
+	printDiffLines(w, '+', newLines[start:newEnd])
+
+	return true
+}
+
+func checkEmbed(w io.Writer, ef embedFile) bool {
+	existing, err := os.ReadFile(ef.Filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(w, "%s is missing\n", ef.Filename)
+
+		return true
+	}
+
+	e(err)
+
+	if bytes.Equal(existing, ef.Data) {
+		return false
+	}
+
+	fmt.Fprintf(w, "%s is out of date: existing file is %d bytes, generated data is %d bytes\n", ef.Filename, len(existing), len(ef.Data))
+
+	return true
+}
+
+// checkEmbedDir reports any files in the embed directory that would not be
+// written by the current run, such as those left by a removed asset.
+func checkEmbedDir(w io.Writer, output, dir string, embeds []embedFile) bool {
+	generated := make(map[string]bool, len(embeds))
+
+	for _, ef := range embeds {
+		generated[ef.Filename] = true
+	}
+
+	var stale bool
+
+	root := filepath.Join(filepath.Dir(output), filepath.FromSlash(path.Clean(filepath.ToSlash(dir))))
+
+	err := filepath.WalkDir(root, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && !generated[fp] {
+			fmt.Fprintf(w, "%s is not part of the generated output\n", fp)
+
+			stale = true
+		}
+
+		return nil
+	})
+	if !errors.Is(err, fs.ErrNotExist) {
+		e(err)
+	}
+
+	return stale
+}
+
+func lineRange(start, end int) string {
+	if end-start == 1 {
+		return fmt.Sprintf("line %d", end)
+	}
+
+	return fmt.Sprintf("lines %d-%d", start+1, end)
+}
+
+func printDiffLines(w io.Writer, prefix byte, lines [][]byte) {
+	for n, line := range lines {
+		if n == maxDiffLines {
+			fmt.Fprintf(w, "%c ... (%d more lines)\n", prefix, len(lines)-n)
+
+			break
+		}
+
+		if len(line) > maxDiffWidth {
+			fmt.Fprintf(w, "%c %s... (%d bytes)\n", prefix, line[:maxDiffWidth], len(line))
+		} else {
+			fmt.Fprintf(w, "%c %s\n", prefix, line)
+		}
+	}
+}
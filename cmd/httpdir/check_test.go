@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	for n, test := range [...]struct {
+		Existing, Generated string
+		Stale               bool
+		Output              string
+	}{
+		{
+			Existing:  "a\nb\nc\n",
+			Generated: "a\nb\nc\n",
+		},
+		{
+			Existing:  "a\nb\nc\n",
+			Generated: "a\nB\nc\n",
+			Stale:     true,
+			Output:    "line 2 of existing file differ from line 2 of generated output\n- b\n+ B\n",
+		},
+		{
+			Existing:  "a\nb\nc\nd\n",
+			Generated: "a\nB\nC\nd\n",
+			Stale:     true,
+			Output:    "lines 2-3 of existing file differ from lines 2-3 of generated output\n- b\n- c\n+ B\n+ C\n",
+		},
+		{
+			Existing:  "a\nc\n",
+			Generated: "a\nb\nc\n",
+			Stale:     true,
+			Output:    "line 2 of generated output inserted at line 2 of existing file\n+ b\n",
+		},
+		{
+			Existing:  "a\nb\nc\nd\n",
+			Generated: "a\nd\n",
+			Stale:     true,
+			Output:    "lines 2-3 of existing file removed at line 2 of generated output\n- b\n- c\n",
+		},
+	} {
+		if err := os.WriteFile(filename, []byte(test.Existing), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var sb strings.Builder
+
+		if stale := checkFile(&sb, filename, []byte(test.Generated)); stale != test.Stale {
+			t.Errorf("test %d: expecting stale %v, got %v", n+1, test.Stale, stale)
+		}
+
+		if test.Output != "" {
+			test.Output = filename + " is out of date: " + test.Output
+		}
+
+		if output := sb.String(); output != test.Output {
+			t.Errorf("test %d: expecting output %q, got %q", n+1, test.Output, output)
+		}
+	}
+}
+
+func TestCheckEmbed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.gz")
+
+	if err := os.WriteFile(filename, []byte("12345"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for n, test := range [...]struct {
+		Filename, Data string
+		Stale          bool
+		Output         string
+	}{
+		{
+			Filename: filename,
+			Data:     "12345",
+		},
+		{
+			Filename: filename,
+			Data:     "1234",
+			Stale:    true,
+			Output:   filename + " is out of date: existing file is 5 bytes, generated data is 4 bytes\n",
+		},
+		{
+			Filename: filename + ".br",
+			Data:     "12345",
+			Stale:    true,
+			Output:   filename + ".br is missing\n",
+		},
+	} {
+		var sb strings.Builder
+
+		if stale := checkEmbed(&sb, embedFile{Filename: test.Filename, Data: []byte(test.Data)}); stale != test.Stale {
+			t.Errorf("test %d: expecting stale %v, got %v", n+1, test.Stale, stale)
+		}
+
+		if output := sb.String(); output != test.Output {
+			t.Errorf("test %d: expecting output %q, got %q", n+1, test.Output, output)
+		}
+	}
+}
+
+func TestCheckEmbedDir(t *testing.T) {
+	base := t.TempDir()
+	output := filepath.Join(base, "assets.go")
+
+	var embeds []embedFile
+
+	for _, name := range [...]string{"index.html", "js/app.js", "js/app.js.gz"} {
+		embeds = append(embeds, embedFile{Filename: filepath.Join(base, "data", filepath.FromSlash(name))})
+	}
+
+	var sb strings.Builder
+
+	if checkEmbedDir(&sb, output, "data", embeds) {
+		t.Errorf("test 1: expecting no stale files for a missing directory")
+	}
+
+	for _, name := range [...]string{"index.html", "js/app.js", "js/app.js.gz", "js/old.js", "old.css"} {
+		name = filepath.Join(base, "data", filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !checkEmbedDir(&sb, output, "./data/", embeds) {
+		t.Errorf("test 2: expecting stale files")
+	}
+
+	expected := filepath.Join(base, "data", "js", "old.js") + " is not part of the generated output\n" + filepath.Join(base, "data", "old.css") + " is not part of the generated output\n"
+
+	if output := sb.String(); output != expected {
+		t.Errorf("test 2: expecting output %q, got %q", expected, output)
+	}
+}
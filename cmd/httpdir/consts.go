@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// constName creates an identifier from the last n segments of the given web
+// path, with each word capitalised and the extension upper-cased, such that
+// /js/app.js would become AppJS for n = 1, and JsAppJS for n = 2.
+func constName(prefix, webPath string, n int) string {
+	segments := strings.Split(strings.Trim(webPath, "/"), "/")
+	if n < len(segments) {
+		segments = segments[len(segments)-n:]
+	}
+
+	var name strings.Builder
+
+	name.WriteString(prefix)
+
+	for s, segment := range segments {
+		ext := ""
+
+		if s == len(segments)-1 {
+			if ext = path.Ext(segment); ext == segment {
+				ext = ""
+			}
+
+			segment = strings.TrimSuffix(segment, ext)
+		}
+
+		for _, word := range strings.FieldsFunc(segment, notAlnum) {
+			r, size := utf8.DecodeRuneInString(word)
+
+			name.WriteRune(unicode.ToUpper(r))
+			name.WriteString(word[size:])
+		}
+
+		for _, word := range strings.FieldsFunc(ext, notAlnum) {
+			name.WriteString(strings.ToUpper(word))
+		}
+	}
+
+	if r, _ := utf8.DecodeRuneInString(name.String()); !unicode.IsLetter(r) {
+		return "Asset" + name.String()
+	}
+
+	return name.String()
+}
+
+func notAlnum(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// constNames returns a unique identifier for each of the given web paths,
+// adding parent directories to the names of those that would otherwise
+// clash, and numbering those that still clash.
+func constNames(prefix string, paths []string) []string {
+	var (
+		names    = make([]string, len(paths))
+		segments = make([]int, len(paths))
+	)
+
+	for n := range segments {
+		segments[n] = 1
+	}
+
+	for {
+		groups := map[string][]int{}
+
+		for n, p := range paths {
+			names[n] = constName(prefix, p, segments[n])
+			groups[names[n]] = append(groups[names[n]], n)
+		}
+
+		changed := false
+
+		for _, group := range groups {
+			if len(group) == 1 {
+				continue
+			}
+
+			for _, n := range group {
+				if segments[n] < strings.Count(strings.Trim(paths[n], "/"), "/")+1 {
+					segments[n]++
+					changed = true
+				}
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	seen := map[string]int{}
+
+	for n, name := range names {
+		if seen[name]++; seen[name] > 1 {
+			names[n] = name + strconv.Itoa(seen[name])
+		}
+	}
+
+	return names
+}
+
+// constPaths returns the logical web path of each asset, from which its
+// constant name is made.
+func constPaths(assets []asset) []string {
+	paths := make([]string, len(assets))
+
+	for n, a := range assets {
+		if a.Name != "" {
+			paths[n] = a.Name
+		} else {
+			paths[n] = a.Path
+		}
+	}
+
+	return paths
+}
+
+// checkConstNames returns an error if the name of any generated constant is
+// the same as that of another package level identifier used by the generated
+// code.
+func checkConstNames(prefix, output string, dirs []directory, assets []asset, embeds []embedFile) error {
+	idents := map[string]string{}
+
+	if *registerFn != "" {
+		idents[*registerFn] = "-register function"
+
+		if *newFn != "" {
+			idents[*newFn] = "-new function"
+		}
+
+		if len(dirs) > 0 {
+			idents[registerName("")] = "register function for the directories"
+		}
+
+		for _, a := range assets {
+			idents[registerName(a.Path)] = "register function for " + a.Path
+		}
+	} else {
+		for _, d := range dirs {
+			for _, v := range d.Vars {
+				if token.IsIdentifier(v) {
+					idents[v] = "-v or -c variable"
+				}
+			}
+		}
+
+		for _, a := range assets {
+			for _, v := range [...]string{a.Opts.Var, a.Opts.CVar} {
+				if token.IsIdentifier(v) {
+					idents[v] = "-v or -c variable"
+				}
+			}
+		}
+	}
+
+	if *fingerprintMap != "" && len(fingerprints(assets)) > 0 {
+		idents[*fingerprintMap] = "-fingerprintmap variable"
+	}
+
+	for _, ef := range embeds {
+		idents[ef.Var] = "embed variable for " + ef.Filename
+	}
+
+	if *genTest {
+		idents[testFuncName(output)] = "generated test function"
+	}
+
+	paths := constPaths(assets)
+
+	for n, name := range constNames(prefix, paths) {
+		if what, ok := idents[name]; ok {
+			return fmt.Errorf("%s: constant name %q clashes with the %s; use -constprefix to change it", paths[n], name, what)
+		}
+	}
+
+	return nil
+}
+
+func writeConsts(w io.Writer, prefix string, assets []asset) {
+	if len(assets) == 0 {
+		return
+	}
+
+	paths := constPaths(assets)
+	names := constNames(prefix, paths)
+
+	var buf bytes.Buffer
+
+	buf.WriteString(constsStart)
+
+	for n, name := range names {
+		if !token.IsIdentifier(name) {
+			e(fmt.Errorf("%s: invalid constant name: %q", paths[n], name))
+		}
+
+		fmt.Fprintf(&buf, constEntry, name, assets[n].Path)
+	}
+
+	buf.WriteString(constsEnd)
+
+	src, err := format.Source(buf.Bytes())
+	e(err)
+
+	ne(io.WriteString(w, "\n"))
+	ne(w.Write(src))
+}
+
+const (
+	constsStart = `// Web paths of the generated assets.
+const (
+`
+	constEntry = `	%s = %q
+`
+	constsEnd = `)
+`
+)
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConstName(t *testing.T) {
+	for n, test := range [...]struct {
+		Prefix, Path string
+		Segments     int
+		Name         string
+	}{
+		{Path: "/js/app.js", Segments: 1, Name: "AppJS"},
+		{Path: "/js/app.js", Segments: 2, Name: "JsAppJS"},
+		{Path: "/js/app.js", Segments: 3, Name: "JsAppJS"},
+		{Path: "/css/main-style.min.css", Segments: 1, Name: "MainStyleMinCSS"},
+		{Path: "/index.html", Segments: 1, Name: "IndexHTML"},
+		{Path: "/.htaccess", Segments: 1, Name: "Htaccess"},
+		{Path: "/LICENSE", Segments: 1, Name: "LICENSE"},
+		{Path: "/fonts/font.woff2", Segments: 1, Name: "FontWOFF2"},
+		{Path: "/404.html", Segments: 1, Name: "Asset404HTML"},
+		{Path: "/ñame.txt", Segments: 1, Name: "ÑameTXT"},
+		{Prefix: "path", Path: "/404.html", Segments: 1, Name: "path404HTML"},
+		{Prefix: "Path", Path: "/app.js", Segments: 1, Name: "PathAppJS"},
+	} {
+		if name := constName(test.Prefix, test.Path, test.Segments); name != test.Name {
+			t.Errorf("test %d: expecting name %q, got %q", n+1, test.Name, name)
+		}
+	}
+}
+
+func TestConstNames(t *testing.T) {
+	for n, test := range [...]struct {
+		Paths, Names []string
+	}{
+		{
+			Paths: []string{"/index.html", "/js/app.js", "/css/style.css"},
+			Names: []string{"IndexHTML", "AppJS", "StyleCSS"},
+		},
+		{
+			Paths: []string{"/index.html", "/docs/index.html", "/js/app.js"},
+			Names: []string{"IndexHTML", "DocsIndexHTML", "AppJS"},
+		},
+		{
+			Paths: []string{"/a/x/app.js", "/b/x/app.js", "/app.js"},
+			Names: []string{"AXAppJS", "BXAppJS", "AppJS"},
+		},
+		{
+			Paths: []string{"/app.js", "/app-js", "/app_js"},
+			Names: []string{"AppJS", "AppJs", "AppJs2"},
+		},
+	} {
+		if names := constNames("", test.Paths); !reflect.DeepEqual(names, test.Names) {
+			t.Errorf("test %d: expecting names %q, got %q", n+1, test.Names, names)
+		}
+	}
+}
+
+func TestCheckConstNames(t *testing.T) {
+	defer func(register, new, fingerprintMapName string, test bool) {
+		*registerFn, *newFn, *fingerprintMap, *genTest = register, new, fingerprintMapName, test
+	}(*registerFn, *newFn, *fingerprintMap, *genTest)
+
+	for n, test := range [...]struct {
+		Prefix, Register, New, FingerprintMap string
+		Test                                  bool
+		Dirs                                  []directory
+		Assets                                []asset
+		Err                                   string
+	}{
+		{ // 1
+			Dirs:   []directory{{Path: "/assets", Vars: []string{"httpdir.Default"}}},
+			Assets: []asset{{Path: "/assets/app.js", Opts: options{Var: "httpdir.Default", CVar: "httpdir.Default"}}},
+		},
+		{ // 2
+			Dirs:   []directory{{Path: "/js", Vars: []string{"Assets"}}},
+			Assets: []asset{{Path: "/assets", Opts: options{Var: "Assets", CVar: "Assets"}}},
+			Err:    `/assets: constant name "Assets" clashes with the -v or -c variable; use -constprefix to change it`,
+		},
+		{ // 3
+			Prefix: "Path",
+			Assets: []asset{{Path: "/assets", Opts: options{Var: "Assets", CVar: "Assets"}}},
+		},
+		{ // 4
+			Register: "Register",
+			Assets:   []asset{{Path: "/register", Opts: options{Var: "d", CVar: "d"}}},
+			Err:      `/register: constant name "Register" clashes with the -register function; use -constprefix to change it`,
+		},
+		{ // 5
+			Register: "Register",
+			New:      "New",
+			Assets:   []asset{{Path: "/new", Opts: options{Var: "d", CVar: "d"}}},
+			Err:      `/new: constant name "New" clashes with the -new function; use -constprefix to change it`,
+		},
+		{ // 6
+			FingerprintMap: "Fingerprints",
+			Assets:         []asset{{Path: "/fingerprints.0123456789", Name: "/fingerprints"}},
+			Err:            `/fingerprints: constant name "Fingerprints" clashes with the -fingerprintmap variable; use -constprefix to change it`,
+		},
+		{ // 7
+			FingerprintMap: "Fingerprints",
+			Assets:         []asset{{Path: "/fingerprints"}},
+		},
+		{ // 8
+			Prefix: "Test",
+			Test:   true,
+			Assets: []asset{{Path: "/httpdir-assets"}},
+			Err:    `/httpdir-assets: constant name "TestHttpdirAssets" clashes with the generated test function; use -constprefix to change it`,
+		},
+	} {
+		*registerFn, *newFn, *fingerprintMap, *genTest = test.Register, test.New, test.FingerprintMap, test.Test
+
+		err := checkConstNames(test.Prefix, "assets.go", test.Dirs, test.Assets, nil)
+		if test.Err == "" && err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if test.Err != "" && (err == nil || err.Error() != test.Err) {
+			t.Errorf("test %d: expecting error %q, got %v", n+1, test.Err, err)
+		}
+	}
+
+	*registerFn, *newFn, *fingerprintMap, *genTest = "", "", "", false
+
+	embeds := []embedFile{{Var: "httpdirEmbed_0abc", Filename: "data/a.txt"}}
+
+	if err := checkConstNames("httpdirEmbed_", "assets.go", nil, []asset{{Path: "/0abc"}}, embeds); err == nil {
+		t.Errorf("expecting error for clash with embed variable")
+	}
+}
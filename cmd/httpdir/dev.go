@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// devFilename returns the name of the development file generated alongside the
+// given output file.
+func devFilename(output string) string {
+	return strings.TrimSuffix(output, ".go") + "_dev.go"
+}
+
+// writeDev writes a companion to the generated output, built with the dev
+// tag, that serves each asset from its source file on disk, with the same
+// functions, vars and constants as the generated output.
+func writeDev(w io.Writer, output string, dirs []directory, assets []asset) {
+	if output == "" || output == "-" {
+		e(errors.New("dev mode requires an output filename"))
+	}
+
+	abs, err := filepath.Abs(output)
+	e(err)
+
+	base := filepath.Dir(abs)
+	imset := map[string]struct{}{httpdirImport: {}}
+	files := make([]string, len(assets))
+
+	for n, a := range assets {
+		if a.File == "" {
+			imset[timeImport] = struct{}{}
+
+			continue
+		}
+
+		file, err := filepath.Abs(a.File)
+		e(err)
+
+		rel, err := filepath.Rel(base, file)
+		e(err)
+
+		files[n] = filepath.ToSlash(rel)
+		imset["\"path/filepath\""] = struct{}{}
+		imset["\"runtime\""] = struct{}{}
+	}
+
+	if len(dirs) > 0 || *newFn != "" {
+		imset[timeImport] = struct{}{}
+	}
+
+	if *registerFn != "" && (len(dirs) > 0 || len(assets) > 0) {
+		imset[fmtImport] = struct{}{}
+	}
+
+	im := make(imports, 0, len(imset))
+
+	for i := range imset {
+		im = append(im, i)
+	}
+
+	ne(io.WriteString(w, devBuild))
+	ne(fmt.Fprintf(w, packageStart, *pkg))
+	writeImportList(w, im)
+	ne(io.WriteString(w, importsEnd))
+
+	if *registerFn != "" {
+		ne(fmt.Fprintf(w, devRegisterStart, *registerFn))
+	} else {
+		ne(io.WriteString(w, devInitStart))
+	}
+
+	if _, ok := imset["\"runtime\""]; ok {
+		ne(io.WriteString(w, devBase))
+	}
+
+	for _, d := range dirs {
+		for _, v := range d.Vars {
+			ne(fmt.Fprintf(w, mkdir, v, d.Path, d.Date, d.Index, failure(d.Path)))
+		}
+	}
+
+	for n, a := range assets {
+		paths := []string{a.Path}
+		if a.Name != "" {
+			paths = append(paths, a.Name)
+		}
+
+		for _, p := range paths {
+			if files[n] != "" {
+				ne(fmt.Fprintf(w, devCreateFile, a.Opts.Var, p, files[n], failure(p)))
+			} else {
+				for _, enc := range a.Encs {
+					if enc.Ext == "" {
+						ne(fmt.Fprintf(w, devCreateString, a.Opts.Var, p, string(enc.Buffer), a.Date, failure(p)))
+					}
+				}
+			}
+		}
+	}
+
+	writeEnd(w)
+
+	if *registerFn != "" && *newFn != "" {
+		var date int64
+
+		for _, d := range dirs {
+			if d.Date > date {
+				date = d.Date
+			}
+		}
+
+		for _, a := range assets {
+			if a.Date > date {
+				date = a.Date
+			}
+		}
+
+		ne(fmt.Fprintf(w, newFunc, *newFn, *registerFn, date))
+	}
+
+	writeFingerprints(w, *fingerprintMap, assets)
+
+	if *constsFl {
+		writeConsts(w, *constPrefix, assets)
+	}
+}
+
+const (
+	devBuild = `//go:build dev
+
+`
+	notDevBuild = `//go:build !dev
+
+`
+	devInitStart = `
+// Files are served from their location relative to this source file.
+func init() {
+`
+	devRegisterStart = `
+// %s adds the files and directories to the given Dir, serving the files from
+// their location relative to this source file.
+func %[1]s(d httpdir.Dir) error {
+`
+	devBase = `	_, file, _, _ := runtime.Caller(0)
+	base := filepath.Dir(file)
+`
+	devCreateFile = `	if err := %s.Create(%q, httpdir.OSFile(filepath.Join(base, %q))); err != nil {
+		%s
+	}
+`
+	devCreateString = `	if err := %s.Create(%q, httpdir.FileString(%q, time.Unix(%d, 0))); err != nil {
+		%s
+	}
+`
+)
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type embedFile struct {
+	Var, Pattern, Filename string
+	Data                   []byte
+}
+
+func (ef embedFile) write() {
+	e(os.MkdirAll(filepath.Dir(ef.Filename), 0o755))
+	e(os.WriteFile(ef.Filename, ef.Data, 0o644))
+}
+
+func embedAssets(assets []asset, output, dir string) []embedFile {
+	if output == "" || output == "-" {
+		e(errors.New("embed mode requires an output filename"))
+	}
+
+	dir = path.Clean(filepath.ToSlash(dir))
+	if dir == "." || strings.HasPrefix(dir, "../") || dir == ".." || path.IsAbs(dir) {
+		e(fmt.Errorf("invalid embed directory: %q", dir))
+	}
+
+	base := filepath.Dir(output)
+
+	var embeds []embedFile
+
+	for n := range assets {
+		a := &assets[n]
+		encs := a.Encs
+
+		if a.single() {
+			encs = encs[:1]
+		}
+
+		a.Embeds = make([]string, len(encs))
+
+		for m, enc := range encs {
+			name := strings.TrimPrefix(a.Path+enc.Ext, "/")
+			if !embeddable(name) {
+				name = "_" + hash(a.Path) + enc.Ext
+			}
+
+			pattern := dir + "/" + name
+			ef := embedFile{
+				Var:      "httpdirEmbed_" + hash(pattern),
+				Pattern:  pattern,
+				Filename: filepath.Join(base, filepath.FromSlash(pattern)),
+				Data:     enc.Buffer,
+			}
+			a.Embeds[m] = ef.Var
+			embeds = append(embeds, ef)
+		}
+	}
+
+	return embeds
+}
+
+func hash(s string) string {
+	h := fnv.New64a()
+
+	io.WriteString(h, s)
+
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func embeddable(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("/._-+~,@=", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeEmbeds(w io.Writer, embeds []embedFile) {
+	for _, ef := range embeds {
+		ne(fmt.Fprintf(w, embedVar, ef.Pattern, ef.Var))
+	}
+}
+
+const embedVar = `
+//go:embed %s
+var %s string
+`
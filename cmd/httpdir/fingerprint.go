@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const fingerprintBytes = 4
+
+// fingerprint inserts a hash of the data into the filename of the web path,
+// before the extension.
+//
+// HTML files are the entry points that reference fingerprinted assets, and so
+// keep their names.
+func fingerprint(webPath string, data []byte) string {
+	if !fingerprinted(webPath) {
+		return webPath
+	}
+
+	dir, file := path.Split(webPath)
+	ext := path.Ext(file)
+
+	if ext == file {
+		ext = ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return dir + strings.TrimSuffix(file, ext) + "." + hex.EncodeToString(sum[:fingerprintBytes]) + ext
+}
+
+func fingerprinted(webPath string) bool {
+	switch strings.ToLower(path.Ext(webPath)) {
+	case ".html", ".htm":
+		return false
+	}
+
+	return true
+}
+
+// fingerprints returns the map of logical to fingerprinted web paths.
+func fingerprints(assets []asset) map[string]string {
+	fps := map[string]string{}
+
+	for _, a := range assets {
+		if a.Name != "" {
+			fps[a.Name] = a.Path
+		}
+	}
+
+	return fps
+}
+
+// fingerprintAsset creates an asset, at the given web path, containing a JSON
+// object that maps the logical web paths of the fingerprinted assets to their
+// fingerprinted paths.
+func fingerprintAsset(assets []asset, webPath string, o options) (asset, bool) {
+	fps := fingerprints(assets)
+	if len(fps) == 0 {
+		return asset{}, false
+	}
+
+	data, err := json.Marshal(fps)
+	e(err)
+
+	var date int64
+
+	for _, a := range assets {
+		if a.Name != "" && a.Date > date {
+			date = a.Date
+		}
+	}
+
+	o.Fingerprint = false
+	o.Minify = false
+	o.MinifyType = ""
+
+	return newAsset(data, webPath, date, o), true
+}
+
+func writeFingerprints(w io.Writer, name string, assets []asset) {
+	fps := fingerprints(assets)
+	if len(fps) == 0 || name == "" {
+		return
+	}
+
+	if !token.IsIdentifier(name) {
+		e(fmt.Errorf("invalid fingerprint map name: %q", name))
+	}
+
+	names := make([]string, 0, len(fps))
+
+	for name := range fps {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, fingerprintStart, name)
+
+	for _, n := range names {
+		fmt.Fprintf(&buf, fingerprintEntry, n, fps[n])
+	}
+
+	buf.WriteString(fingerprintEnd)
+
+	src, err := format.Source(buf.Bytes())
+	e(err)
+
+	ne(io.WriteString(w, "\n"))
+	ne(w.Write(src))
+}
+
+const (
+	fingerprintStart = `// %s maps the logical web path of each fingerprinted asset to its
+// fingerprinted web path.
+var %[1]s = map[string]string{
+`
+	fingerprintEntry = `	%q: %q,
+`
+	fingerprintEnd = `}
+`
+)
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	for n, test := range [...]struct {
+		Path, Data, Output string
+	}{
+		{Path: "/app.js", Data: "a", Output: "/app.ca978112.js"},
+		{Path: "/app.js", Data: "b", Output: "/app.3e23e816.js"},
+		{Path: "/js/app.min.js", Data: "a", Output: "/js/app.min.ca978112.js"},
+		{Path: "/LICENSE", Data: "a", Output: "/LICENSE.ca978112"},
+		{Path: "/.htaccess", Data: "a", Output: "/.htaccess.ca978112"},
+		{Path: "/index.html", Data: "a", Output: "/index.html"},
+		{Path: "/about.HTM", Data: "a", Output: "/about.HTM"},
+	} {
+		if out := fingerprint(test.Path, []byte(test.Data)); out != test.Output {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Output, out)
+		}
+	}
+}
+
+func TestFingerprints(t *testing.T) {
+	fps := fingerprints([]asset{
+		{Path: "/index.html"},
+		{Path: "/app.ca978112.js", Name: "/app.js"},
+		{Path: "/css/style.3e23e816.css", Name: "/css/style.css"},
+	})
+
+	if len(fps) != 2 {
+		t.Errorf("expecting 2 fingerprints, got %d", len(fps))
+	}
+
+	for name, path := range map[string]string{
+		"/app.js":        "/app.ca978112.js",
+		"/css/style.css": "/css/style.3e23e816.css",
+	} {
+		if fps[name] != path {
+			t.Errorf("expecting %q to map to %q, got %q", name, path, fps[name])
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailure(t *testing.T) {
+	defer func(fn string) { *registerFn = fn }(*registerFn)
+
+	for n, test := range [...]struct {
+		Register, Path, Statement string
+	}{
+		{Path: "/index.html", Statement: `panic("httpdir: /index.html: " + err.Error())`},
+		{Path: "/100%\"a\".txt", Statement: `panic("httpdir: /100%\"a\".txt: " + err.Error())`},
+		{Register: "Register", Path: "/index.html", Statement: `return fmt.Errorf("httpdir: /index.html: %w", err)`},
+		{Register: "Register", Path: "/100%\"a\".txt", Statement: `return fmt.Errorf("httpdir: /100%%\"a\".txt: %w", err)`},
+	} {
+		*registerFn = test.Register
+
+		if statement := failure(test.Path); statement != test.Statement {
+			t.Errorf("test %d: expecting statement %s, got %s", n+1, test.Statement, statement)
+		}
+	}
+}
+
+// buildGenerator builds the generator into a temporary directory, returning
+// its path and a function to run go commands in this directory.
+func buildGenerator(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("skipping build of generated code in short mode")
+	}
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+
+		if output, err := exec.Command(goCmd, args...).CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %s\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	generator := filepath.Join(t.TempDir(), "httpdir")
+
+	run("build", "-o", generator, ".")
+
+	return generator, run
+}
+
+// generatedDir creates a directory for a generated package, which must be
+// within this module so that it imports this version of httpdir.
+func generatedDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp(".", "_generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var testSite = map[string]string{
+	"index.html":    "<!DOCTYPE html><link rel=\"stylesheet\" href=\"css/style.css\"><script src=\"js/app.js\"></script>",
+	"css/style.css": "body {\n\tbackground: url(\"../img/bg.png\");\n}\n",
+	"js/app.js":     strings.Repeat("console.log(\"Hello, World!\");\n", 20),
+	"img/bg.png":    "\x89PNG\r\n\x1a\n\x00\x00\x00",
+	"100%\"a\".txt": strings.Repeat("a", 100),
+}
+
+func TestGeneratedCodeBuilds(t *testing.T) {
+	generator, run := buildGenerator(t)
+	input := filepath.Join(t.TempDir(), "site")
+	pkgDir := generatedDir(t)
+	pkg := "./" + filepath.Base(pkgDir)
+
+	writeTree(t, input, testSite)
+
+	for n, test := range [...]struct {
+		Args []string
+		Tags string
+	}{
+		{Args: []string{"-g", "-b", "-B", "-f", "-zstd", "-test"}},
+		{Args: []string{"-g", "-z", "-s", "-chunk", "16", "-test"}},
+		{Args: []string{"-g", "-b", "-B", "-register", "Register", "-new", "New", "-test"}},
+		{Args: []string{"-g", "-s", "-e", "data", "-consts", "-fingerprint", "-fingerprintjson", "/fingerprints.json"}},
+		{Args: []string{"-g", "-minify", "-register", "Register", "-dev", "-test"}, Tags: "dev"},
+	} {
+		if err := os.RemoveAll(pkgDir); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Mkdir(pkgDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		args := append([]string{"-r", "-p", "assets", "-d", "1", "-i", input, "-o", filepath.Join(pkgDir, "assets.go")}, test.Args...)
+
+		if output, err := exec.Command(generator, args...).CombinedOutput(); err != nil {
+			t.Fatalf("test %d: generator: %s\n%s", n+1, err, output)
+		}
+
+		run("vet", pkg)
+		run("test", pkg)
+
+		if test.Tags != "" {
+			run("vet", "-tags", test.Tags, pkg)
+		}
+	}
+}
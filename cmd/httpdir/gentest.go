@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// testFilename returns the name of the test file generated alongside the given
+// output file.
+func testFilename(output string) string {
+	return strings.TrimSuffix(output, ".go") + "_test.go"
+}
+
+// testFuncName returns the name of the generated test function.
+func testFuncName(output string) string {
+	return constName("TestHttpdir", strings.TrimSuffix(filepath.Base(output), ".go"), 1)
+}
+
+var testDecoders = map[string]struct {
+	Import, Decoder string
+}{
+	".br":  {brotliPureImport, "r = brotli.NewReader(data)"},
+	".fl":  {flateImport, "r = flate.NewReader(data)"},
+	".gz":  {gzipImport, "r, err = gzip.NewReader(data)"},
+	".zst": {zstdImport, "r, err = zstd.NewReader(data)"},
+}
+
+// writeTest writes a test that checks that every compressed variant of the
+// generated assets decompresses to the same data as the uncompressed node.
+func writeTest(w io.Writer, output string, assets []asset) {
+	var (
+		buf     bytes.Buffer
+		exts    = map[string]bool{}
+		cbrotli bool
+		im      = imports{"\"bytes\"", ioImport, "\"testing\"", httpdirImport}
+	)
+
+	if *registerFn != "" {
+		im = append(im, timeImport)
+	}
+
+	for _, a := range assets {
+		for _, enc := range a.Encs {
+			if enc.Ext == ".br" && !a.Opts.PureGoBrotli {
+				cbrotli = true
+			}
+
+			if enc.Ext != "" {
+				exts[enc.Ext] = true
+			}
+		}
+	}
+
+	for ext := range exts {
+		if d, ok := testDecoders[ext]; ok {
+			if ext == ".br" && cbrotli {
+				d.Import = brotliImport
+			}
+
+			im = append(im, d.Import)
+		}
+	}
+
+	name := testFuncName(output)
+
+	if *devFl {
+		buf.WriteString(notDevBuild)
+	}
+
+	fmt.Fprintf(&buf, packageStart, *pkg)
+
+	if len(exts) == 0 {
+		fmt.Fprintf(&buf, testSkip, name)
+		ne(w.Write(buf.Bytes()))
+
+		return
+	}
+
+	writeImportList(&buf, im)
+	fmt.Fprintf(&buf, testStart, importsEnd, name)
+
+	if *registerFn != "" {
+		fmt.Fprintf(&buf, testRegister, *registerFn)
+	}
+
+	buf.WriteString(testTable)
+
+	for _, a := range assets {
+		cvar := a.Opts.Var
+		if a.single() {
+			cvar = a.Opts.CVar
+		}
+
+		for _, enc := range a.Encs {
+			if enc.Ext != "" {
+				fmt.Fprintf(&buf, testEntry, a.Opts.Var, cvar, a.Path, enc.Ext)
+			}
+		}
+	}
+
+	buf.WriteString(testLoop)
+
+	for _, ext := range [...]string{".br", ".fl", ".gz", ".zst"} {
+		if exts[ext] {
+			decoder := testDecoders[ext].Decoder
+			if ext == ".br" && cbrotli {
+				decoder = "r = cbrotli.NewReader(data)"
+			}
+
+			fmt.Fprintf(&buf, testCase, ext, decoder)
+		}
+	}
+
+	buf.WriteString(testEnd)
+
+	src, err := format.Source(buf.Bytes())
+	e(err)
+
+	ne(w.Write(src))
+}
+
+const (
+	testSkip = `	"testing"
+)
+
+func %s(t *testing.T) {
+	t.Skip("no compressed variants")
+}
+`
+	testStart = `%s
+func %s(t *testing.T) {
+	read := func(d httpdir.Dir, name string) []byte {
+		t.Helper()
+
+		f, err := d.Open(name)
+		if err != nil {
+			t.Fatalf("%%s: error opening: %%s", name, err)
+		}
+
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("%%s: error reading: %%s", name, err)
+		}
+
+		return data
+	}
+`
+	testRegister = `
+	d := httpdir.New(time.Now())
+
+	if err := %s(d); err != nil {
+		t.Fatalf("unexpected error registering assets: %%s", err)
+	}
+`
+	testTable = `
+	for _, test := range [...]struct {
+		Dir, CDir httpdir.Dir
+		Path, Ext string
+	}{
+`
+	testEntry = `		{%s, %s, %q, %q},
+`
+	testLoop = `	} {
+		var (
+			name = test.Path + test.Ext
+			data = bytes.NewReader(read(test.CDir, name))
+			r    io.Reader
+			err  error
+		)
+
+		switch test.Ext {
+`
+	testCase = `		case %q:
+			%s
+`
+	testEnd = `		}
+
+		if err != nil {
+			t.Errorf("%s: error decompressing: %s", name, err)
+
+			continue
+		}
+
+		decompressed, err := io.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: error decompressing: %s", name, err)
+		} else if !bytes.Equal(decompressed, read(test.Dir, test.Path)) {
+			t.Errorf("%s: decompressed data does not match %s", name, test.Path)
+		}
+	}
+}
+`
+)
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are the files, read from each directory in recursive mode when
+// ignore files are enabled, that list patterns of files to skip.
+var ignoreFiles = [...]string{".gitignore", ".httpdirignore"}
+
+// pattern is a single gitignore style pattern.
+type pattern struct {
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// patterns is a list of patterns, in which the last matching pattern takes
+// precedence.
+type patterns []pattern
+
+// parsePattern parses a single line using gitignore semantics, relative to the
+// slash separated base directory. The returned bool is false for blank lines
+// and comments.
+func parsePattern(base, line string) (pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || line[0] == '#' {
+		return pattern{}, false
+	}
+
+	p := pattern{base: base}
+
+	if line[0] == '!' {
+		p.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' && len(line) > 1 && (line[1] == '!' || line[1] == '#') {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if line == "" {
+		return pattern{}, false
+	}
+
+	if !strings.Contains(line, "/") {
+		p.segments = append(p.segments, "**")
+	}
+
+	for _, s := range strings.Split(strings.TrimPrefix(line, "/"), "/") {
+		if s != "" {
+			p.segments = append(p.segments, s)
+		}
+	}
+
+	return p, true
+}
+
+// parsePatterns parses each line of the data as a pattern.
+func parsePatterns(base, data string) patterns {
+	var ps patterns
+
+	for _, line := range strings.Split(data, "\n") {
+		if p, ok := parsePattern(base, line); ok {
+			ps = append(ps, p)
+		}
+	}
+
+	return ps
+}
+
+// match determines whether the slash separated path, relative to the root of
+// the walk, matches the pattern.
+func (p pattern) match(rel string, dir bool) bool {
+	if p.dirOnly && !dir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+
+		rel = rel[len(p.base)+1:]
+	}
+
+	return matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 0 {
+				return len(name) > 0
+			}
+
+			for n := range name {
+				if matchSegments(pattern, name[n:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// match returns whether any pattern matched the path and, if so, whether the
+// last matching pattern was a negation.
+func (ps patterns) match(rel string, dir bool) (matched, negated bool) {
+	for n := len(ps) - 1; n >= 0; n-- {
+		if ps[n].match(rel, dir) {
+			return true, ps[n].negate
+		}
+	}
+
+	return false, false
+}
+
+// filter decides which files are read from a directory in recursive mode.
+type filter struct {
+	include, exclude, ignore patterns
+	ignoreFiles              bool
+}
+
+func newFilter(o options) filter {
+	f := filter{ignoreFiles: o.Ignore}
+
+	for _, glob := range o.Include {
+		if p, ok := parsePattern("", glob); ok {
+			f.include = append(f.include, p)
+		}
+	}
+
+	for _, glob := range o.Exclude {
+		if p, ok := parsePattern("", glob); ok {
+			f.exclude = append(f.exclude, p)
+		}
+	}
+
+	return f
+}
+
+// readIgnore adds the patterns from any ignore files in the given directory,
+// which is at the slash separated rel path from the root.
+func (f *filter) readIgnore(dir, rel string) error {
+	if !f.ignoreFiles {
+		return nil
+	}
+
+	if rel == "." {
+		rel = ""
+	}
+
+	for _, name := range ignoreFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		f.ignore = append(f.ignore, parsePatterns(rel, string(data))...)
+	}
+
+	return nil
+}
+
+// skip returns true if the file or directory, at the slash separated rel path
+// from the root, should not be read. Directories are only subject to the
+// exclude and ignore patterns, so that included files may be found within.
+func (f *filter) skip(rel string, dir bool) bool {
+	if f.ignoreFiles && !dir {
+		for _, name := range ignoreFiles {
+			if path.Base(rel) == name {
+				return true
+			}
+		}
+	}
+
+	if matched, negated := f.ignore.match(rel, dir); matched && !negated {
+		return true
+	}
+
+	if matched, negated := f.exclude.match(rel, dir); matched && !negated {
+		return true
+	}
+
+	if !dir && len(f.include) > 0 {
+		matched, negated := f.include.match(rel, dir)
+
+		return !matched || negated
+	}
+
+	return false
+}
+
+// splitGlobs splits a comma separated list of globs.
+func splitGlobs(list string) []string {
+	var globs []string
+
+	for _, glob := range strings.Split(list, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+
+	return globs
+}
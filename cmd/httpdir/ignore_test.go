@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	for n, test := range [...]struct {
+		Base, Patterns, Path string
+		Dir                  bool
+		Matched, Negated     bool
+	}{
+		{Patterns: "*.map", Path: "app.js.map", Matched: true},
+		{Patterns: "*.map", Path: "js/app.js.map", Matched: true},
+		{Patterns: "*.map", Path: "app.js"},
+		{Patterns: ".DS_Store", Path: "a/b/.DS_Store", Matched: true},
+		{Patterns: "/app.js", Path: "app.js", Matched: true},
+		{Patterns: "/app.js", Path: "js/app.js"},
+		{Patterns: "js/*.js", Path: "js/app.js", Matched: true},
+		{Patterns: "js/*.js", Path: "js/lib/app.js"},
+		{Patterns: "js/*.js", Path: "src/js/app.js"},
+		{Patterns: "js/**/*.js", Path: "js/app.js", Matched: true},
+		{Patterns: "js/**/*.js", Path: "js/lib/a/app.js", Matched: true},
+		{Patterns: "**/test", Path: "a/test", Dir: true, Matched: true},
+		{Patterns: "js/**", Path: "js/app.js", Matched: true},
+		{Patterns: "js/**", Path: "js", Dir: true},
+		{Patterns: "tests/", Path: "tests", Dir: true, Matched: true},
+		{Patterns: "tests/", Path: "tests"},
+		{Patterns: "# comment\n\n*.js", Path: "# comment"},
+		{Patterns: "\\#file", Path: "#file", Matched: true},
+		{Patterns: "\\!file", Path: "!file", Matched: true},
+		{Patterns: "*.js  \r", Path: "app.js", Matched: true},
+		{Patterns: "file\\ ", Path: "file ", Matched: true},
+		{Patterns: "*.js\n!keep.js", Path: "keep.js", Matched: true, Negated: true},
+		{Patterns: "*.js\n!keep.js", Path: "app.js", Matched: true},
+		{Patterns: "!keep.js\n*.js", Path: "keep.js", Matched: true},
+		{Patterns: "file[0-9].txt", Path: "file1.txt", Matched: true},
+		{Patterns: "file?.txt", Path: "file/.txt"},
+		{Base: "js", Patterns: "*.map", Path: "js/app.js.map", Matched: true},
+		{Base: "js", Patterns: "*.map", Path: "app.js.map"},
+		{Base: "js", Patterns: "/app.js", Path: "js/app.js", Matched: true},
+		{Base: "js", Patterns: "/app.js", Path: "js/lib/app.js"},
+	} {
+		matched, negated := parsePatterns(test.Base, test.Patterns).match(test.Path, test.Dir)
+		if matched != test.Matched || negated != test.Negated {
+			t.Errorf("test %d: expecting matched %v and negated %v, got %v and %v", n+1, test.Matched, test.Negated, matched, negated)
+		}
+	}
+}
+
+func TestReadDirFilter(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, contents := range map[string]string{
+		".gitignore":            "*.map\nbuild/\n!keep.map\n",
+		".DS_Store":             "",
+		"index.html":            "",
+		"app.js":                "",
+		"app.js.map":            "",
+		"keep.map":              "",
+		"app_test.js":           "",
+		"build/out.js":          "",
+		"js/.httpdirignore":     "/local.js\n!*.map\n",
+		"js/local.js":           "",
+		"js/lib.js":             "",
+		"js/lib.js.map":         "",
+		"js/sub/local.js":       "",
+		"node_modules/lib/a.js": "",
+	} {
+		name = filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n, test := range [...]struct {
+		Include, Exclude []string
+		Ignore           bool
+		Paths            []string
+	}{
+		{
+			Include: []string{"*.js"},
+			Exclude: []string{"*_test.js", "node_modules/"},
+			Paths:   []string{"/app.js", "/build/out.js", "/js/lib.js", "/js/local.js", "/js/sub/local.js"},
+		},
+		{
+			Exclude: []string{".*", "node_modules"},
+			Ignore:  true,
+			Paths:   []string{"/app.js", "/app_test.js", "/index.html", "/js/lib.js", "/js/lib.js.map", "/js/sub/local.js", "/keep.map"},
+		},
+		{
+			Include: []string{"**/*.map"},
+			Ignore:  true,
+			Paths:   []string{"/js/lib.js.map", "/keep.map"},
+		},
+	} {
+		_, assets := readDir(dir, "", options{Include: test.Include, Exclude: test.Exclude, Ignore: test.Ignore})
+
+		var paths []string
+
+		for _, a := range assets {
+			paths = append(paths, a.Path)
+		}
+
+		if !reflect.DeepEqual(paths, test.Paths) {
+			t.Errorf("test %d: expecting paths %q, got %q", n+1, test.Paths, paths)
+		}
+	}
+}
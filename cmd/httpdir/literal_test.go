@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// evalLiteral returns the value of a generated string literal, a concatenation
+// of literals, or a slice of either.
+func evalLiteral(t *testing.T, src string) (string, int) {
+	t.Helper()
+
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", src, err)
+	}
+
+	var (
+		buf    strings.Builder
+		chunks int
+	)
+
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BasicLit:
+			if n.Kind != token.STRING {
+				t.Fatalf("unexpected literal: %s", n.Value)
+			}
+
+			s, err := strconv.Unquote(n.Value)
+			if err != nil {
+				t.Fatalf("error unquoting %s: %s", n.Value, err)
+			}
+
+			buf.WriteString(s)
+		case *ast.CompositeLit:
+			chunks = len(n.Elts)
+		}
+
+		return true
+	})
+
+	return buf.String(), chunks
+}
+
+func TestWriteLiteral(t *testing.T) {
+	random := make([]byte, 4096)
+
+	rand.New(rand.NewSource(0)).Read(random)
+
+	for n, test := range [...]string{
+		"",
+		"Hello, World!",
+		"line\nbreak\r\n",
+		"`",
+		"a`b``",
+		"ends with \r",
+		"\x00NUL\x00",
+		"\uFEFFBOM\uFEFF",
+		"\uFFFDreplacement\uFFFD",
+		"\xff\xfe invalid \xc3",
+		"ñ日本語😀",
+		string(random),
+	} {
+		for _, tick := range [...]bool{false, true} {
+			var buf bytes.Buffer
+
+			writeLiteral(&buf, []byte(test), tick)
+
+			if bytes.ContainsAny(buf.Bytes(), "\x00\uFEFF") {
+				t.Errorf("test %d (tick: %v): literal contains NUL or BOM", n+1, tick)
+			}
+
+			if got, _ := evalLiteral(t, buf.String()); got != test {
+				t.Errorf("test %d (tick: %v): expecting %q, got %q", n+1, tick, test, got)
+			}
+		}
+	}
+}
+
+func TestWriteDataChunked(t *testing.T) {
+	for n, test := range [...]struct {
+		Data      string
+		ChunkSize int
+		Chunks    int
+	}{
+		{Data: "abcdef", ChunkSize: 0, Chunks: 0},
+		{Data: "abcdef", ChunkSize: 6, Chunks: 0},
+		{Data: "abcdef", ChunkSize: 5, Chunks: 2},
+		{Data: "abcdef", ChunkSize: 2, Chunks: 3},
+		{Data: "ab`\r`\x00ef", ChunkSize: 3, Chunks: 3},
+		{Data: "日本語", ChunkSize: 4, Chunks: 3},
+		{Data: "a日本語", ChunkSize: 4, Chunks: 3},
+		{Data: "a日本語", ChunkSize: 2, Chunks: 7},
+		{Data: "\xff\xff\xff\xff", ChunkSize: 2, Chunks: 2},
+	} {
+		for _, ext := range [...]string{"", ".gz"} {
+			a := asset{
+				Encs: encodings{{Buffer: []byte(test.Data), Ext: ext}},
+				Opts: options{ChunkSize: test.ChunkSize},
+			}
+
+			var buf bytes.Buffer
+
+			a.writeData(&buf, 0)
+
+			got, chunks := evalLiteral(t, buf.String())
+			if got != test.Data {
+				t.Errorf("test %d (ext %q): expecting %q, got %q", n+1, ext, test.Data, got)
+			} else if chunks != test.Chunks {
+				t.Errorf("test %d (ext %q): expecting %d chunks, got %d", n+1, ext, test.Chunks, chunks)
+			} else if a.chunked(0) != (test.Chunks > 0) {
+				t.Errorf("test %d (ext %q): expecting chunked to be %v", n+1, ext, test.Chunks > 0)
+			}
+		}
+	}
+}
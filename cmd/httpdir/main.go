@@ -3,10 +3,14 @@ package main // import "vimagination.zapto.org/httpdir/cmd/httpdir"
 import (
 	"compress/flate"
 	"compress/gzip"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,28 +18,55 @@ import (
 	"unicode/utf8"
 
 	"github.com/foobaz/go-zopfli/zopfli"
-	"github.com/google/brotli/go/cbrotli"
+	"github.com/klauspost/compress/zstd"
 	"vimagination.zapto.org/memio"
 )
 
 var (
-	pkg      = flag.String("p", "main", "package name")
-	in       = flag.String("i", "", "input filename")
-	out      = flag.String("o", "", "output filename")
-	odate    = flag.String("d", "", "modified date [seconds since epoch]")
-	path     = flag.String("w", "", "http path")
-	varname  = flag.String("v", "httpdir.Default", "http dir variable name")
-	cvarname = flag.String("c", "httpdir.Default", "http dir compressed variable name")
-	help     = flag.Bool("h", false, "show help")
-	gzcomp   = flag.Bool("g", false, "compress using gzip")
-	brcomp   = flag.Bool("b", false, "compress using brotli")
-	flcomp   = flag.Bool("f", false, "compress using flate/deflate")
-	single   = flag.Bool("s", false, "use single source var and decompress/compress for others")
-	zpfcomp  = flag.Bool("z", false, "replace gzip with zopfli compression")
+	pkg            = flag.String("p", "main", "package name")
+	in             = flag.String("i", "", "input filename")
+	out            = flag.String("o", "", "output filename")
+	odate          = flag.String("d", "", "modified date [seconds since epoch]")
+	webPath        = flag.String("w", "", "http path (prefix in recursive mode)")
+	varname        = flag.String("v", "httpdir.Default", "http dir variable name")
+	cvarname       = flag.String("c", "httpdir.Default", "http dir compressed variable name")
+	help           = flag.Bool("h", false, "show help")
+	gzcomp         = flag.Bool("g", false, "compress using gzip")
+	brcomp         = flag.Bool("b", false, "compress using brotli")
+	purebr         = flag.Bool("B", false, "use pure-Go brotli implementation, without cgo, in generator and generated code (generators built without cgo always compress with pure-Go brotli)")
+	flcomp         = flag.Bool("f", false, "compress using flate/deflate")
+	single         = flag.Bool("s", false, "use single source var and decompress/compress for others")
+	zpfcomp        = flag.Bool("z", false, "replace gzip with zopfli compression")
+	zstdcomp       = flag.Bool("zstd", false, "compress using zstandard")
+	minBytes       = flag.Int("minbytes", 0, "drop compressed variants saving fewer than this many bytes (0 to disable)")
+	minRatio       = flag.Float64("minratio", 0, "drop compressed variants saving less than this fraction of the original size (0 to disable)")
+	skipCompressed = flag.Bool("skipcompressed", false, "do not compress files with already-compressed MIME types (images, fonts, archives, etc.)")
+	minifyFiles    = flag.Bool("minify", false, "minify HTML, CSS, JS, JSON and SVG files, selected by extension, before compression")
+	minifyType     = flag.String("minifytype", "", "minify all input using the given minifier (html, css, js, json or svg), regardless of extension")
+	fingerprintFl  = flag.Bool("fingerprint", false, "insert a hash of the contents into the web path of each non-HTML file, rewriting HTML and CSS references in recursive mode")
+	fingerprintMap = flag.String("fingerprintmap", "Fingerprints", "name of the generated map var from logical to fingerprinted web paths (empty for none)")
+	fingerprintWeb = flag.String("fingerprintjson", "", "web path of a generated JSON file mapping logical to fingerprinted web paths")
+	constsFl       = flag.Bool("consts", false, "generate a string constant for the web path of each asset, such as AppJS for /js/app.js")
+	constPrefix    = flag.String("constprefix", "", "prefix for generated constant names (a lowercase prefix makes them unexported)")
+	registerFn     = flag.String("register", "", "generate a function with this name, taking an httpdir.Dir to which the assets are added, instead of init functions; -v and -c are ignored")
+	newFn          = flag.String("new", "", "with -register, also generate a function with this name that returns a new httpdir.Dir containing the assets")
+	include        = flag.String("include", "", "in recursive mode, only embed files matching one of these comma separated gitignore style globs")
+	exclude        = flag.String("exclude", "", "in recursive mode, skip files and directories matching any of these comma separated gitignore style globs")
+	ignore         = flag.Bool("ignore", false, "in recursive mode, skip files listed in .gitignore and .httpdirignore files, which are not themselves embedded")
+	chunkSize      = flag.Int("chunk", 1<<20, "split literals larger than this many bytes into a slice of smaller literals (0 to disable)")
+	devFl          = flag.Bool("dev", false, "generate a companion file, built with the dev tag, serving each asset from its source file on disk")
+	genTest        = flag.Bool("test", false, "generate a test, alongside the output file, checking that every compressed variant decompresses correctly")
+	reportFile     = flag.String("report", "", "write a report of the size of each asset and its compressed variants to this file (- for stdout, which requires -o); not written with -check")
+	reportFormat   = flag.String("reportformat", "table", "format of the report written with -report (table or json)")
+	recursive      = flag.Bool("r", false, "treat input as a directory, embedding all files recursively and following symbolic links to files")
+	index          = flag.Bool("l", false, "allow directory listings for directories created in recursive mode")
+	manifestFile   = flag.String("m", "", "JSON manifest file describing the assets to generate")
+	embedDir       = flag.String("e", "", "write data to files in this directory, relative to the output file, and reference them with go:embed")
+	checkOutput    = flag.Bool("check", false, "check that the output file is up-to-date instead of writing it (use with -d or manifest dates for reproducible output)")
 )
 
 type replacer struct {
-	f *os.File
+	f io.Writer
 }
 
 var hexArr = [16]byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'A', 'B', 'C', 'D', 'E', 'F'}
@@ -50,7 +81,9 @@ func (r replacer) Write(p []byte) (int, error) {
 			s = 1
 		}
 
-		if s > 1 || (p[0] > 0 && p[0] < 0x7f && p[0] != '\n' && p[0] != '\\' && p[0] != '"') {
+		if rn == '\uFEFF' {
+			toWrite = append(toWrite[:0], '\\', 'u', 'F', 'E', 'F', 'F')
+		} else if s > 1 || (p[0] > 0 && p[0] < 0x7f && p[0] != '\n' && p[0] != '\\' && p[0] != '"') {
 			toWrite = append(toWrite[:0], p[:s]...)
 		} else {
 			switch p[0] {
@@ -78,7 +111,7 @@ func (r replacer) Write(p []byte) (int, error) {
 }
 
 type tickReplacer struct {
-	f *os.File
+	f io.Writer
 }
 
 func (r tickReplacer) Write(p []byte) (int, error) {
@@ -90,45 +123,54 @@ func (r tickReplacer) Write(p []byte) (int, error) {
 	hexes := make([]byte, 0, 32)
 	toWrite := make([]byte, 0, 1024)
 
-	for len(p) > 0 {
-		dr, s := utf8.DecodeRune(p)
-		if dr == utf8.RuneError || dr == '`' || dr == '\r' {
-			hexes = append(hexes, p[0])
-		} else {
-			if len(hexes) > 0 {
-				toWrite = toWrite[:0]
-				toWrite = append(toWrite, '`', '+', '"')
-
-				for _, b := range hexes {
-					if b == '`' {
-						toWrite = append(toWrite, '`')
-					} else if b == '\r' {
-						toWrite = append(toWrite, '\\', 'r')
-					} else {
-						toWrite = append(toWrite, '\\', 'x', hexArr[b>>4], hexArr[b&15])
-					}
-				}
+	flush := func() error {
+		if len(hexes) == 0 {
+			return nil
+		}
 
-				toWrite = append(toWrite, '"', '+', '`')
-				n += len(hexes)
+		toWrite = toWrite[:0]
+		toWrite = append(toWrite, '`', '+', '"')
 
-				if _, err = r.f.Write(toWrite); err != nil {
-					break
-				}
+		for _, b := range hexes {
+			if b == '`' {
+				toWrite = append(toWrite, '`')
+			} else if b == '\r' {
+				toWrite = append(toWrite, '\\', 'r')
+			} else {
+				toWrite = append(toWrite, '\\', 'x', hexArr[b>>4], hexArr[b&15])
+			}
+		}
+
+		toWrite = append(toWrite, '"', '+', '`')
+		n += len(hexes)
+		hexes = hexes[:0]
+
+		_, err := r.f.Write(toWrite)
+
+		return err
+	}
 
-				hexes = hexes[:0]
+	for len(p) > 0 {
+		dr, s := utf8.DecodeRune(p)
+		if dr == utf8.RuneError || dr == '`' || dr == '\r' || dr == 0 || dr == '\uFEFF' {
+			hexes = append(hexes, p[:s]...)
+		} else {
+			if err = flush(); err != nil {
+				return n, err
 			}
 
 			m, err = r.f.Write(p[:s])
 			n += m
 
 			if err != nil {
-				break
+				return n, err
 			}
 		}
+
 		p = p[s:]
 	}
-	return n, err
+
+	return n, flush()
 }
 
 func e(err error) {
@@ -148,14 +190,20 @@ func (im imports) Len() int {
 	return len(im)
 }
 
+func importPath(i string) string {
+	return i[strings.IndexByte(i, '"'):]
+}
+
 func (im imports) Less(i, j int) bool {
-	si := strings.HasPrefix(im[i], "\"github.com")
-	sj := strings.HasPrefix(im[j], "\"github.com")
-	vi := strings.HasPrefix(im[i], "\"vimagination.zapto.org")
-	vj := strings.HasPrefix(im[j], "\"vimagination.zapto.org")
+	pi := importPath(im[i])
+	pj := importPath(im[j])
+	si := strings.HasPrefix(pi, "\"github.com")
+	sj := strings.HasPrefix(pj, "\"github.com")
+	vi := strings.HasPrefix(pi, "\"vimagination.zapto.org")
+	vj := strings.HasPrefix(pj, "\"vimagination.zapto.org")
 
 	if si == sj && vi == vj {
-		return im[i] < im[j]
+		return pi < pj
 	}
 
 	return !si && !vi || si && vj
@@ -166,8 +214,9 @@ func (im imports) Swap(i, j int) {
 }
 
 type encoding struct {
-	Buffer                    []byte
-	Compress, Decompress, Ext string
+	Buffer                             []byte
+	Compress, Decompress, Ext          string
+	CompressImports, DecompressImports []string
 }
 
 type encodings []encoding
@@ -184,43 +233,85 @@ func (e encodings) Swap(i, j int) {
 	e[i], e[j] = e[j], e[i]
 }
 
-func main() {
-	flag.Parse()
+type options struct {
+	Gzip, Brotli, Flate, Zopfli, Zstd bool
+	Single                            bool
+	PureGoBrotli                      bool
+	Index                             bool
+	SkipCompressedTypes               bool
+	MinSavingsBytes                   int
+	MinSavingsRatio                   float64
+	Minify                            bool
+	MinifyType                        string
+	Fingerprint                       bool
+	ChunkSize                         int
+	Include, Exclude                  []string
+	Ignore                            bool
+	Var, CVar                         string
+	Date                              *int64
+}
 
-	if *help {
-		flag.Usage()
-		return
+func flagOptions() options {
+	o := options{
+		Gzip:                *gzcomp,
+		Brotli:              *brcomp,
+		PureGoBrotli:        *purebr,
+		Flate:               *flcomp,
+		Zopfli:              *zpfcomp,
+		Zstd:                *zstdcomp,
+		Single:              *single,
+		Index:               *index,
+		SkipCompressedTypes: *skipCompressed,
+		MinSavingsBytes:     *minBytes,
+		MinSavingsRatio:     *minRatio,
+		Minify:              *minifyFiles,
+		MinifyType:          *minifyType,
+		Fingerprint:         *fingerprintFl,
+		ChunkSize:           *chunkSize,
+		Include:             splitGlobs(*include),
+		Exclude:             splitGlobs(*exclude),
+		Ignore:              *ignore,
+		Var:                 *varname,
+		CVar:                *cvarname,
 	}
 
-	var (
-		f    *os.File
-		err  error
-		date int64
-	)
-
-	if *in == "-" || *in == "" {
-		f = os.Stdin
-		date = time.Now().Unix()
-	} else {
-		f, err = os.Open(*in)
-		e(err)
-		fi, err := f.Stat()
-		e(err)
-		date = fi.ModTime().Unix()
-	}
 	if *odate != "" {
-		date, err = strconv.ParseInt(*odate, 10, 64)
+		date, err := strconv.ParseInt(*odate, 10, 64)
 		e(err)
+
+		o.Date = &date
 	}
 
-	data := make(memio.Buffer, 0, 1<<20)
+	if _, ok := minifiers[o.MinifyType]; !ok && o.MinifyType != "" {
+		e(fmt.Errorf("unknown minifier: %q", o.MinifyType))
+	}
 
-	_, err = io.Copy(&data, f)
-	e(err)
-	e(f.Close())
+	return o
+}
+
+func (o options) compressed() bool {
+	return o.Gzip || o.Brotli || o.Flate || o.Zopfli || o.Zstd
+}
 
-	im := imports{"\"vimagination.zapto.org/httpdir\"", "\"time\""}
+type asset struct {
+	Path   string
+	Name   string
+	File   string
+	Date   int64
+	Size   int
+	Encs   encodings
+	Opts   options
+	Embeds []string
+}
+
+type directory struct {
+	Path  string
+	Date  int64
+	Index bool
+	Vars  []string
+}
 
+func compress(data []byte, o options) encodings {
 	encs := make(encodings, 1, 4)
 	encs[0] = encoding{
 		Buffer:     data,
@@ -229,46 +320,67 @@ func main() {
 		Ext:        "",
 	}
 
-	if *brcomp {
+	if o.Brotli {
 		var b memio.Buffer
 
-		br := cbrotli.NewWriter(&b, cbrotli.WriterOptions{Quality: 11})
-		br.Write(data)
-		br.Close()
-
-		if *single {
-			im = append(im, brotliImport)
+		encodeBrotli(&b, data, o.PureGoBrotli)
+
+		if o.PureGoBrotli {
+			encs = append(encs, encoding{
+				Buffer:            b,
+				Compress:          brotliPureCompress,
+				Decompress:        brotliPureDecompress,
+				Ext:               ".br",
+				CompressImports:   []string{brotliPureImport, memioImport},
+				DecompressImports: []string{brotliPureImport, stringsImport, ioImport},
+			})
+		} else {
+			encs = append(encs, encoding{
+				Buffer:            b,
+				Compress:          brotliCompress,
+				Decompress:        brotliDecompress,
+				Ext:               ".br",
+				CompressImports:   []string{brotliImport, memioImport},
+				DecompressImports: []string{brotliImport, stringsImport, ioImport},
+			})
 		}
-
-		encs = append(encs, encoding{
-			Buffer:     b,
-			Compress:   brotliCompress,
-			Decompress: brotliDecompress,
-			Ext:        ".br",
-		})
 	}
-	if *flcomp {
+	if o.Flate {
 		var b memio.Buffer
 
 		fl, _ := flate.NewWriter(&b, flate.BestCompression)
 		fl.Write(data)
 		fl.Close()
 
-		if *single {
-			im = append(im, strings.Split(flateImport, "\n")...)
-		}
+		encs = append(encs, encoding{
+			Buffer:            b,
+			Compress:          flateCompress,
+			Decompress:        flateDecompress,
+			Ext:               ".fl",
+			CompressImports:   []string{flateImport, memioImport},
+			DecompressImports: []string{flateImport, stringsImport, ioImport},
+		})
+	}
+	if o.Zstd {
+		var b memio.Buffer
+
+		zs, _ := zstd.NewWriter(&b, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		zs.Write(data)
+		zs.Close()
 
 		encs = append(encs, encoding{
-			Buffer:     b,
-			Compress:   flateCompress,
-			Decompress: flateDecompress,
-			Ext:        ".fl",
+			Buffer:            b,
+			Compress:          zstdCompress,
+			Decompress:        zstdDecompress,
+			Ext:               ".zst",
+			CompressImports:   []string{zstdImport, memioImport},
+			DecompressImports: []string{zstdImport, stringsImport, ioImport},
 		})
 	}
-	if *gzcomp || *zpfcomp {
+	if o.Gzip || o.Zopfli {
 		var b memio.Buffer
 
-		if *zpfcomp {
+		if o.Zopfli {
 			zopfli.GzipCompress(&zopfli.Options{
 				NumIterations:  100,
 				BlockSplitting: true,
@@ -280,180 +392,777 @@ func main() {
 			gz.Close()
 		}
 
-		if *single {
-			im = append(im, gzipImport)
-		}
-
 		encs = append(encs, encoding{
-			Buffer:     b,
-			Compress:   gzipCompress,
-			Decompress: gzipDecompress,
-			Ext:        ".gz",
+			Buffer:            b,
+			Compress:          gzipCompress,
+			Decompress:        gzipDecompress,
+			Ext:               ".gz",
+			CompressImports:   []string{gzipImport, memioImport},
+			DecompressImports: []string{gzipImport, stringsImport, ioImport},
 		})
 	}
 
 	sort.Sort(encs)
 
-	if *single && (*gzcomp || *brcomp || *flcomp) {
-		im = append(im, "\"vimagination.zapto.org/memio\"")
-		if encs[0].Ext != "" {
-			im = append(im, "\"strings\"")
-		}
+	return encs
+}
+
+func readAsset(f *os.File, webPath string, date int64, o options) asset {
+	return newAsset(readData(f), webPath, date, o)
+}
+
+func readData(f *os.File) []byte {
+	data := make(memio.Buffer, 0, 1<<20)
+
+	_, err := io.Copy(&data, f)
+	e(err)
+	e(f.Close())
+
+	return data
+}
+
+func newAsset(data []byte, webPath string, date int64, o options) asset {
+	if o.Date != nil {
+		date = *o.Date
 	}
 
-	if encs[0].Ext == ".fl" {
-		im = append(im, "\"io\"")
+	if o.Minify || o.MinifyType != "" {
+		data = minify(data, webPath, o.MinifyType)
 	}
 
-	sort.Sort(im)
+	a := asset{
+		Path: webPath,
+		Date: date,
+		Size: len(data),
+		Opts: o,
+	}
 
-	var (
-		imports string
-		ext     bool
-	)
+	start := time.Now()
+	a.Encs = compress(data, o)
+	compressTime += time.Since(start)
 
-	for _, i := range im {
-		if !ext && (strings.HasPrefix(i, "\"github.com") || strings.HasPrefix(i, "\"vimagination")) {
-			imports += "\n"
-			ext = true
+	if o.Fingerprint {
+		if fp := fingerprint(webPath, data); fp != webPath {
+			a.Path = fp
+			a.Name = webPath
 		}
-		imports += "	" + i + "\n"
 	}
 
-	if *out == "-" || *out == "" {
-		f = os.Stdout
+	a.dropVariants()
+
+	return a
+}
+
+func readFile(filename, webPath string, o options) asset {
+	var (
+		f    *os.File
+		err  error
+		date int64
+	)
+
+	if filename == "-" || filename == "" {
+		f = os.Stdin
+		date = time.Now().Unix()
 	} else {
-		f, err = os.Create(*out)
+		f, err = os.Open(filename)
 		e(err)
+		fi, err := f.Stat()
+		e(err)
+		date = fi.ModTime().Unix()
 	}
 
-	fmt.Fprintf(f, packageStart, *pkg, imports, date)
+	a := readAsset(f, webPath, date, o)
 
-	if *single {
-		f.WriteString(stringStart)
+	if f != os.Stdin {
+		a.File = filename
+	}
 
-		if encs[0].Ext == "" {
-			ne(f.WriteString("`"))
-			ne(tickReplacer{f}.Write(encs[0].Buffer))
-			ne(f.WriteString("`"))
+	return a
+}
+
+func readDir(root, prefix string, o options) ([]directory, []asset) {
+	var (
+		dirs    []directory
+		sources []source
+		entries = map[string]int{}
+	)
+
+	if root == "-" || root == "" {
+		e(errors.New("recursive mode requires an input directory"))
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	vars := []string{o.Var}
+	if o.CVar != o.Var && o.Single && o.compressed() {
+		vars = append(vars, o.CVar)
+	}
+
+	filter := newFilter(o)
+
+	e(filepath.WalkDir(root, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, fp)
+		if err != nil {
+			return err
+		}
+
+		webPath := prefix + "/" + filepath.ToSlash(rel)
+		if rel == "." {
+			webPath = prefix
+		}
+
+		if rel != "." {
+			entries[path.Dir(webPath)]++
+
+			if filter.skip(filepath.ToSlash(rel), d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			if fi, err = os.Stat(fp); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: skipped: %s\n", fp, errors.Unwrap(err))
+
+				return nil
+			} else if fi.IsDir() {
+				fmt.Fprintf(os.Stderr, "%s: skipped: symbolic link to a directory\n", fp)
+
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			if err := filter.readIgnore(fp, filepath.ToSlash(rel)); err != nil {
+				return err
+			}
+
+			if webPath != "" {
+				date := fi.ModTime().Unix()
+				if o.Date != nil {
+					date = *o.Date
+				}
+
+				dirs = append(dirs, directory{Path: webPath, Date: date, Index: o.Index, Vars: vars})
+			}
+		} else if fi.Mode().IsRegular() {
+			f, err := os.Open(fp)
+			if err != nil {
+				return err
+			}
+
+			sources = append(sources, source{Path: webPath, File: fp, Date: fi.ModTime().Unix(), Data: readData(f)})
 		} else {
-			ne(f.WriteString("\""))
-			ne(replacer{f}.Write(encs[0].Buffer))
-			ne(f.WriteString("\""))
+			fmt.Fprintf(os.Stderr, "%s: skipped: not a regular file\n", fp)
 		}
 
-		f.WriteString(stringEnd)
+		return nil
+	}))
+
+	dirs = pruneDirs(dirs, sources, entries, prefix)
 
-		for n, enc := range encs {
+	if o.Fingerprint {
+		return dirs, rewriteAssets(sources, o)
+	}
+
+	assets := make([]asset, len(sources))
+
+	for n, s := range sources {
+		assets[n] = newAsset(s.Data, s.Path, s.Date, o)
+		assets[n].File = s.File
+	}
+
+	return dirs, assets
+}
+
+// pruneDirs removes the directories, other than the root, that contain no
+// files once filtered, while keeping those that are empty in the source tree.
+func pruneDirs(dirs []directory, sources []source, entries map[string]int, root string) []directory {
+	keep := map[string]bool{root: true}
+
+	mark := func(p string) {
+		for ; !keep[p] && p != "/"; p = path.Dir(p) {
+			keep[p] = true
+		}
+	}
+
+	for _, s := range sources {
+		mark(path.Dir(s.Path))
+	}
+
+	for _, d := range dirs {
+		if entries[d.Path] == 0 {
+			mark(d.Path)
+		}
+	}
+
+	pruned := dirs[:0]
+
+	for _, d := range dirs {
+		if keep[d.Path] {
+			pruned = append(pruned, d)
+		}
+	}
+
+	return pruned
+}
+
+// single returns true if the asset should be written as a single source var,
+// which is only useful when there are other variants to derive from it.
+func (a asset) single() bool {
+	return a.Opts.Single && len(a.Encs) > 1
+}
+
+func (a asset) imports() []string {
+	im := []string{httpdirImport, timeImport}
+
+	if !a.single() {
+		return im
+	}
+
+	if a.chunked(0) {
+		im = append(im, stringsImport)
+	}
+
+	for n, enc := range a.Encs {
+		if n == 0 {
+			im = append(im, enc.DecompressImports...)
+		} else if a.Encs[0].Ext != "" || enc.Ext != "" {
+			im = append(im, enc.CompressImports...)
+		}
+	}
+
+	return im
+}
+
+func (a asset) write(w io.Writer) {
+	if *registerFn != "" {
+		ne(fmt.Fprintf(w, registerStart, registerName(a.Path), a.Date))
+	} else {
+		ne(fmt.Fprintf(w, initStart, a.Date))
+	}
+
+	if a.single() {
+		if a.chunked(0) {
+			ne(io.WriteString(w, joinStart))
+			a.writeData(w, 0)
+			ne(io.WriteString(w, joinEnd))
+		} else {
+			ne(io.WriteString(w, stringStart))
+			a.writeData(w, 0)
+			ne(io.WriteString(w, stringEnd))
+		}
+
+		for n, enc := range a.Encs {
 			var (
 				templ string
-				vars  = []interface{}{0, *cvarname, *path + enc.Ext}
+				size  = len(enc.Buffer)
+				dir   = a.Opts.CVar
+				name  = a.Path + enc.Ext
 			)
 
 			if enc.Ext == "" {
-				vars = vars[1:]
-				vars[0] = *varname
+				dir = a.Opts.Var
 
 				if n == 0 {
 					templ = identDecompress
 				} else {
 					templ = identCompress
 				}
+			} else if n == 0 {
+				size = a.Size
+				templ = enc.Decompress
 			} else {
-				if n == 0 {
-					vars[0] = len(data)
-					templ = enc.Decompress
-				} else {
-					vars[0] = len(enc.Buffer)
-					templ = enc.Compress
-				}
+				templ = enc.Compress
 			}
 
-			fmt.Fprintf(f, templ, vars...)
+			ne(fmt.Fprintf(w, templ, size, dir, name, failure(name)))
 		}
 	} else {
-		for _, enc := range encs {
-			filename := *path + enc.Ext
-			ne(fmt.Fprintf(f, soloStart, *varname, filename))
+		for n, enc := range a.Encs {
+			node := "FileString"
+			if a.chunked(n) {
+				node = "FileStrings"
+			}
 
-			if enc.Ext == "" {
-				ne(f.WriteString("`"))
-				ne(tickReplacer{f}.Write(enc.Buffer))
-				ne(f.WriteString("`"))
-			} else {
-				ne(f.WriteString("\""))
-				ne(replacer{f}.Write(enc.Buffer))
-				ne(f.WriteString("\""))
+			ne(fmt.Fprintf(w, soloStart, a.Opts.Var, a.Path+enc.Ext, node))
+			a.writeData(w, n)
+			ne(fmt.Fprintf(w, soloEnd, failure(a.Path+enc.Ext)))
+		}
+	}
+
+	writeEnd(w)
+}
+
+// chunked returns true if the data for the nth encoding is to be written as a
+// slice of string literals.
+func (a asset) chunked(n int) bool {
+	return a.Embeds == nil && a.Opts.ChunkSize > 0 && len(a.Encs[n].Buffer) > a.Opts.ChunkSize
+}
+
+func (a asset) writeData(w io.Writer, n int) {
+	if a.Embeds != nil {
+		ne(io.WriteString(w, a.Embeds[n]))
+
+		return
+	}
+
+	data := a.Encs[n].Buffer
+	tick := a.Encs[n].Ext == ""
+
+	if !a.chunked(n) {
+		writeLiteral(w, data, tick)
+
+		return
+	}
+
+	ne(io.WriteString(w, chunksStart))
+
+	for len(data) > 0 {
+		size := a.Opts.ChunkSize
+		if size > len(data) {
+			size = len(data)
+		}
+
+		for c := size; c < len(data) && c > size-utf8.UTFMax && c > 0; c-- {
+			if utf8.RuneStart(data[c]) {
+				size = c
+
+				break
+			}
+		}
+
+		ne(io.WriteString(w, chunkStart))
+		writeLiteral(w, data[:size], tick)
+		ne(io.WriteString(w, chunkEnd))
+
+		data = data[size:]
+	}
+
+	ne(io.WriteString(w, chunksEnd))
+}
+
+func writeLiteral(w io.Writer, data []byte, tick bool) {
+	if tick {
+		ne(io.WriteString(w, "`"))
+		ne(tickReplacer{w}.Write(data))
+		ne(io.WriteString(w, "`"))
+	} else {
+		ne(io.WriteString(w, "\""))
+		ne(replacer{w}.Write(data))
+		ne(io.WriteString(w, "\""))
+	}
+}
+
+// failure returns the statement used by generated code when unable to create
+// the node at the given web path; either a panic, or, in register mode, the
+// return of an error.
+func failure(webPath string) string {
+	msg := "httpdir: " + webPath + ": "
+
+	if *registerFn != "" {
+		return fmt.Sprintf("return fmt.Errorf(%q, err)", strings.ReplaceAll(msg, "%", "%%")+"%w")
+	}
+
+	return fmt.Sprintf("panic(%q + err.Error())", msg)
+}
+
+func writeEnd(w io.Writer) {
+	if *registerFn != "" {
+		ne(io.WriteString(w, registerEnd))
+	} else {
+		ne(io.WriteString(w, initEnd))
+	}
+}
+
+func writeDirs(w io.Writer, dirs []directory) {
+	if len(dirs) == 0 {
+		return
+	}
+
+	if *registerFn != "" {
+		ne(fmt.Fprintf(w, registerDirsStart, registerName("")))
+	} else {
+		ne(io.WriteString(w, dirsStart))
+	}
+
+	for _, d := range dirs {
+		for _, v := range d.Vars {
+			ne(fmt.Fprintf(w, mkdir, v, d.Path, d.Date, d.Index, failure(d.Path)))
+		}
+	}
+
+	writeEnd(w)
+}
+
+func writeImports(w io.Writer, dirs []directory, assets []asset) {
+	imset := map[string]struct{}{}
+
+	if len(dirs) > 0 {
+		imset[httpdirImport] = struct{}{}
+		imset[timeImport] = struct{}{}
+	}
+
+	if *registerFn != "" {
+		imset[httpdirImport] = struct{}{}
+
+		if len(dirs) > 0 || len(assets) > 0 {
+			imset[fmtImport] = struct{}{}
+		}
+
+		if *newFn != "" {
+			imset[timeImport] = struct{}{}
+		}
+	}
+
+	for _, a := range assets {
+		for _, i := range a.imports() {
+			imset[i] = struct{}{}
+		}
+
+		if a.Embeds != nil {
+			imset[embedImport] = struct{}{}
+		}
+	}
+
+	im := make(imports, 0, len(imset))
+
+	for i := range imset {
+		im = append(im, i)
+	}
+
+	writeImportList(w, im)
+}
+
+// writeImportList writes the sorted imports, separating the standard library
+// from external packages.
+func writeImportList(w io.Writer, im imports) {
+	sort.Sort(im)
+
+	var ext bool
+
+	for _, i := range im {
+		if !ext && (strings.HasPrefix(i, "\"github.com") || strings.HasPrefix(i, "\"vimagination")) {
+			ne(io.WriteString(w, "\n"))
+			ext = true
+		}
+
+		ne(io.WriteString(w, "\t"+i+"\n"))
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if *help {
+		flag.Usage()
+		return
+	}
+
+	var (
+		f      *os.File
+		err    error
+		dirs   []directory
+		assets []asset
+	)
+
+	if *manifestFile != "" {
+		dirs, assets = readManifest(*manifestFile)
+	} else if *recursive {
+		dirs, assets = readDir(*in, *webPath, flagOptions())
+	} else {
+		assets = append(assets, readFile(*in, *webPath, flagOptions()))
+	}
+
+	if *fingerprintWeb != "" {
+		if a, ok := fingerprintAsset(assets, *fingerprintWeb, flagOptions()); ok {
+			assets = append(assets, a)
+		}
+	}
+
+	if !*checkOutput {
+		printDropped()
+
+		if *reportFile != "" {
+			if *reportFile == "-" && (*out == "-" || *out == "") {
+				e(errors.New("report cannot be written to stdout along with the generated code"))
 			}
 
-			ne(f.WriteString(soloEnd))
+			writeReport(*reportFile, *reportFormat, assets)
 		}
 	}
 
-	ne(fmt.Fprint(f, packageEnd))
+	setRegisterVars(dirs, assets)
+
+	var embeds []embedFile
+
+	if *embedDir != "" {
+		embeds = embedAssets(assets, *out, *embedDir)
+	}
+
+	if *constsFl {
+		e(checkConstNames(*constPrefix, *out, dirs, assets, embeds))
+	}
+
+	if *checkOutput {
+		var buf memio.Buffer
+
+		generate(&buf, dirs, assets, embeds)
+
+		stale := checkFile(os.Stderr, *out, buf)
+
+		if *devFl {
+			buf = buf[:0]
+
+			writeDev(&buf, *out, dirs, assets)
+
+			if checkFile(os.Stderr, devFilename(*out), buf) {
+				stale = true
+			}
+		}
+
+		if *genTest {
+			buf = buf[:0]
+
+			writeTest(&buf, *out, assets)
+
+			if checkFile(os.Stderr, testFilename(*out), buf) {
+				stale = true
+			}
+		}
+
+		for _, ef := range embeds {
+			if checkEmbed(os.Stderr, ef) {
+				stale = true
+			}
+		}
+
+		if *embedDir != "" && checkEmbedDir(os.Stderr, *out, *embedDir, embeds) {
+			stale = true
+		}
+
+		if stale {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	for _, ef := range embeds {
+		ef.write()
+	}
+
+	if *out == "-" || *out == "" {
+		f = os.Stdout
+	} else {
+		f, err = os.Create(*out)
+		e(err)
+	}
+
+	generate(f, dirs, assets, embeds)
 	e(f.Close())
+
+	if *devFl {
+		f, err = os.Create(devFilename(*out))
+		e(err)
+
+		writeDev(f, *out, dirs, assets)
+		e(f.Close())
+	}
+
+	if *genTest {
+		if *out == "-" || *out == "" {
+			e(errors.New("test generation requires an output filename"))
+		}
+
+		f, err = os.Create(testFilename(*out))
+		e(err)
+
+		writeTest(f, *out, assets)
+		e(f.Close())
+	}
+}
+
+func generate(w io.Writer, dirs []directory, assets []asset, embeds []embedFile) {
+	if *devFl {
+		ne(io.WriteString(w, notDevBuild))
+	}
+
+	ne(fmt.Fprintf(w, packageStart, *pkg))
+	writeImports(w, dirs, assets)
+	ne(io.WriteString(w, importsEnd))
+	writeEmbeds(w, embeds)
+	writeDirs(w, dirs)
+
+	for _, a := range assets {
+		a.write(w)
+	}
+
+	if *registerFn != "" {
+		writeRegister(w, dirs, assets)
+	}
+
+	writeFingerprints(w, *fingerprintMap, assets)
+
+	if *constsFl {
+		writeConsts(w, *constPrefix, assets)
+	}
 }
 
 const (
 	packageStart = `package %s
 
 import (
-%s)
-
+`
+	importsEnd = `)
+`
+	initStart = `
 func init() {
 	date := time.Unix(%d, 0)
+`
+	dirsStart = `
+func init() {
+`
+	mkdir = `	if err := %s.Mkdir(%q, time.Unix(%d, 0), %t); err != nil {
+		%s
+	}
 `
 	stringStart = `	s := `
 	stringEnd   = `
 `
-	packageEnd = `}
+	joinStart = `	s := strings.Join(`
+	joinEnd   = `, "")
 `
-	soloStart = `	%s.Create(%q, httpdir.FileString(`
-	soloEnd   = `, date))
+	chunksStart = `[]string{
 `
-	identDecompress = `	b := []byte(s)
-	%s.Create(%q, httpdir.FileString(s, date))
+	chunkStart = `		`
+	chunkEnd   = `,
+`
+	chunksEnd = `	}`
+	initEnd   = `}
+`
+	registerStart = `
+func %s(d httpdir.Dir) error {
+	date := time.Unix(%d, 0)
+`
+	registerDirsStart = `
+func %s(d httpdir.Dir) error {
+`
+	registerEnd = `
+	return nil
+}
+`
+	soloStart = `	if err := %s.Create(%q, httpdir.%s(`
+	soloEnd   = `, date)); err != nil {
+		%s
+	}
 `
-	identCompress = `	%s.Create(%q, httpdir.FileBytes(b, date))
+	createEnd = `); err != nil {
+		%[4]s
+	}
 `
+	identDecompress = `	b := []byte(s)
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	identCompress = `	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(b, date)` + createEnd
+
+	httpdirImport = "\"vimagination.zapto.org/httpdir\""
+	timeImport    = "\"time\""
+	memioImport   = "\"vimagination.zapto.org/memio\""
+	stringsImport = "\"strings\""
+	ioImport      = "\"io\""
+	fmtImport     = "\"fmt\""
+	embedImport   = "_ \"embed\""
 
 	brotliImport     = "\"github.com/google/brotli/go/cbrotli\""
-	brotliDecompress = `	b := make([]byte, %d)
+	brotliDecompress = `	b := make([]byte, %[1]d)
 	br := cbrotli.NewReader(strings.NewReader(s))
-	br.Read(b)
+	if _, err := io.ReadFull(br, b); err != nil {
+		%[4]s
+	}
 	br.Close()
-	%s.Create(%q, httpdir.FileString(s, date))
-`
-	brotliCompress = `	brb := make(memio.Buffer, 0, %d)
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	brotliCompress = `	brb := make(memio.Buffer, 0, %[1]d)
 	br := cbrotli.NewWriter(&brb, cbrotli.WriterOptions{Quality: 11})
 	br.Write(b)
-	br.Close()
-	%s.Create(%q, httpdir.FileBytes(brb, date))
-`
+	if err := br.Close(); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(brb, date)` + createEnd
+	brotliPureImport     = "\"github.com/andybalholm/brotli\""
+	brotliPureDecompress = `	b := make([]byte, %[1]d)
+	br := brotli.NewReader(strings.NewReader(s))
+	if _, err := io.ReadFull(br, b); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	brotliPureCompress = `	brb := make(memio.Buffer, 0, %[1]d)
+	br := brotli.NewWriterLevel(&brb, brotli.BestCompression)
+	br.Write(b)
+	if err := br.Close(); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(brb, date)` + createEnd
 	flateImport     = "\"compress/flate\""
-	flateDecompress = `	b := make([]byte, %d)
+	flateDecompress = `	b := make([]byte, %[1]d)
 	fl := flate.NewReader(strings.NewReader(s))
-	io.ReadFull(fl, b)
+	if _, err := io.ReadFull(fl, b); err != nil {
+		%[4]s
+	}
 	fl.Close()
-	%s.Create(%q, httpdir.FileString(s, date))
-`
-	flateCompress = `	flb := make(memio.Buffer, 0, %d)
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	flateCompress = `	flb := make(memio.Buffer, 0, %[1]d)
 	fl, _ := flate.NewWriter(&flb, flate.BestCompression)
 	fl.Write(b)
-	fl.Close()
-	%s.Create(%q, httpdir.FileBytes(flb, date))
-`
+	if err := fl.Close(); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(flb, date)` + createEnd
 	gzipImport     = "\"compress/gzip\""
-	gzipDecompress = `	b := make([]byte, %d)
-	gz, _ := gzip.NewReader(strings.NewReader(s))
-	gz.Read(b)
+	gzipDecompress = `	b := make([]byte, %[1]d)
+	gz, err := gzip.NewReader(strings.NewReader(s))
+	if err != nil {
+		%[4]s
+	}
+	if _, err := io.ReadFull(gz, b); err != nil {
+		%[4]s
+	}
 	gz.Close()
-	%s.Create(%q, httpdir.FileString(s, date))
-`
-	gzipCompress = `	gzb := make(memio.Buffer, 0, %d)
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	gzipCompress = `	gzb := make(memio.Buffer, 0, %[1]d)
 	gz, _ := gzip.NewWriterLevel(&gzb, gzip.BestCompression)
 	gz.Write(b)
-	gz.Close()
-	%s.Create(%q, httpdir.FileBytes(gzb, date))
-`
+	if err := gz.Close(); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(gzb, date)` + createEnd
+	zstdImport     = "\"github.com/klauspost/compress/zstd\""
+	zstdDecompress = `	b := make([]byte, %[1]d)
+	zs, err := zstd.NewReader(strings.NewReader(s))
+	if err != nil {
+		%[4]s
+	}
+	if _, err := io.ReadFull(zs, b); err != nil {
+		%[4]s
+	}
+	zs.Close()
+	if err := %[2]s.Create(%[3]q, httpdir.FileString(s, date)` + createEnd
+	zstdCompress = `	zsb := make(memio.Buffer, 0, %[1]d)
+	zs, _ := zstd.NewWriter(&zsb, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	zs.Write(b)
+	if err := zs.Close(); err != nil {
+		%[4]s
+	}
+	if err := %[2]s.Create(%[3]q, httpdir.FileBytes(zsb, date)` + createEnd
 )
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, contents := range map[string]string{
+		"index.html":       "<p>Hello</p>",
+		"js/lib/app.js":    "",
+		"maps/app.js.map":  "",
+		"maps/sub/lib.map": "",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, target := range map[string]string{
+		"link.html": "index.html",
+		"linkdir":   "js",
+		"broken":    "missing",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skip("unable to create symbolic links: ", err)
+		}
+	}
+
+	dirs, assets := readDir(dir, "/static/", options{Exclude: []string{"*.map"}})
+
+	var dirPaths, assetPaths []string
+
+	for _, d := range dirs {
+		dirPaths = append(dirPaths, d.Path)
+	}
+
+	for _, a := range assets {
+		assetPaths = append(assetPaths, a.Path)
+
+		if a.Path == "/static/link.html" && string(a.Encs[0].Buffer) != "<p>Hello</p>" {
+			t.Errorf("expecting linked file contents %q, got %q", "<p>Hello</p>", a.Encs[0].Buffer)
+		}
+	}
+
+	if expected := []string{"/static", "/static/empty", "/static/js", "/static/js/lib"}; !reflect.DeepEqual(dirPaths, expected) {
+		t.Errorf("expecting dirs %q, got %q", expected, dirPaths)
+	}
+
+	if expected := []string{"/static/index.html", "/static/js/lib/app.js", "/static/link.html"}; !reflect.DeepEqual(assetPaths, expected) {
+		t.Errorf("expecting assets %q, got %q", expected, assetPaths)
+	}
+}
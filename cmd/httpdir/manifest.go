@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type manifest struct {
+	Package         string          `json:"package"`
+	Output          string          `json:"output"`
+	FingerprintMap  string          `json:"fingerprintMap"`
+	FingerprintJSON string          `json:"fingerprintJSON"`
+	Consts          bool            `json:"consts"`
+	ConstPrefix     string          `json:"constPrefix"`
+	Register        string          `json:"register"`
+	New             string          `json:"new"`
+	Test            bool            `json:"test"`
+	Dev             bool            `json:"dev"`
+	Report          string          `json:"report"`
+	ReportFormat    string          `json:"reportFormat"`
+	Assets          []manifestEntry `json:"assets"`
+}
+
+type manifestEntry struct {
+	Input         string   `json:"input"`
+	Path          string   `json:"path"`
+	Recursive     bool     `json:"recursive"`
+	Index         *bool    `json:"index"`
+	Encodings     []string `json:"encodings"`
+	Single        *bool    `json:"single"`
+	PureGoBrotli  *bool    `json:"pureGoBrotli"`
+	Var           string   `json:"var"`
+	CompressedVar string   `json:"compressedVar"`
+	Date          *int64   `json:"date"`
+
+	SkipCompressedTypes *bool    `json:"skipCompressedTypes"`
+	MinSavingsBytes     *int     `json:"minSavingsBytes"`
+	MinSavingsRatio     *float64 `json:"minSavingsRatio"`
+
+	Minify     *bool  `json:"minify"`
+	MinifyType string `json:"minifyType"`
+
+	Fingerprint *bool `json:"fingerprint"`
+	ChunkSize   *int  `json:"chunkSize"`
+
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	Ignore  *bool    `json:"ignore"`
+}
+
+func (m manifestEntry) options(o options) (options, error) {
+	if m.Encodings != nil {
+		o.Gzip, o.Brotli, o.Flate, o.Zopfli, o.Zstd = false, false, false, false, false
+
+		for _, enc := range m.Encodings {
+			switch enc {
+			case "gzip":
+				o.Gzip = true
+			case "brotli":
+				o.Brotli = true
+			case "flate":
+				o.Flate = true
+			case "zopfli":
+				o.Zopfli = true
+			case "zstd":
+				o.Zstd = true
+			default:
+				return o, fmt.Errorf("%s: unknown encoding: %q", m.Input, enc)
+			}
+		}
+	}
+
+	if m.Single != nil {
+		o.Single = *m.Single
+	}
+
+	if m.PureGoBrotli != nil {
+		o.PureGoBrotli = *m.PureGoBrotli
+	}
+
+	if m.Var != "" {
+		o.Var = m.Var
+	}
+
+	if m.CompressedVar != "" {
+		o.CVar = m.CompressedVar
+	}
+
+	if m.Date != nil {
+		o.Date = m.Date
+	}
+
+	if m.SkipCompressedTypes != nil {
+		o.SkipCompressedTypes = *m.SkipCompressedTypes
+	}
+
+	if m.MinSavingsBytes != nil {
+		o.MinSavingsBytes = *m.MinSavingsBytes
+	}
+
+	if m.MinSavingsRatio != nil {
+		o.MinSavingsRatio = *m.MinSavingsRatio
+	}
+
+	if m.Minify != nil {
+		o.Minify = *m.Minify
+	}
+
+	if m.MinifyType != "" {
+		if _, ok := minifiers[m.MinifyType]; !ok {
+			return o, fmt.Errorf("%s: unknown minifier: %q", m.Input, m.MinifyType)
+		}
+
+		o.MinifyType = m.MinifyType
+	}
+
+	if m.Fingerprint != nil {
+		o.Fingerprint = *m.Fingerprint
+	}
+
+	if m.ChunkSize != nil {
+		o.ChunkSize = *m.ChunkSize
+	}
+
+	if m.Include != nil {
+		o.Include = m.Include
+	}
+
+	if m.Exclude != nil {
+		o.Exclude = m.Exclude
+	}
+
+	if m.Ignore != nil {
+		o.Ignore = *m.Ignore
+	}
+
+	if m.Index != nil {
+		o.Index = *m.Index
+	}
+
+	return o, nil
+}
+
+// manifestPath resolves a path, given in the manifest, relative to the
+// directory containing the manifest file.
+func manifestPath(manifest, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(filepath.Dir(manifest), name)
+}
+
+func readManifest(filename string) ([]directory, []asset) {
+	f, err := os.Open(filename)
+	e(err)
+
+	var m manifest
+
+	err = json.NewDecoder(f).Decode(&m)
+	e(f.Close())
+	e(err)
+
+	set := map[string]bool{}
+
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if m.Package != "" && !set["p"] {
+		*pkg = m.Package
+	}
+
+	if m.Output != "" && !set["o"] {
+		*out = manifestPath(filename, m.Output)
+	}
+
+	if m.FingerprintMap != "" && !set["fingerprintmap"] {
+		*fingerprintMap = m.FingerprintMap
+	}
+
+	if m.FingerprintJSON != "" && !set["fingerprintjson"] {
+		*fingerprintWeb = m.FingerprintJSON
+	}
+
+	if m.Consts && !set["consts"] {
+		*constsFl = true
+	}
+
+	if m.ConstPrefix != "" && !set["constprefix"] {
+		*constPrefix = m.ConstPrefix
+	}
+
+	if m.Register != "" && !set["register"] {
+		*registerFn = m.Register
+	}
+
+	if m.New != "" && !set["new"] {
+		*newFn = m.New
+	}
+
+	if m.Test && !set["test"] {
+		*genTest = true
+	}
+
+	if m.Dev && !set["dev"] {
+		*devFl = true
+	}
+
+	if m.Report == "-" && !set["report"] {
+		*reportFile = m.Report
+	} else if m.Report != "" && !set["report"] {
+		*reportFile = manifestPath(filename, m.Report)
+	}
+
+	if m.ReportFormat != "" && !set["reportformat"] {
+		*reportFormat = m.ReportFormat
+	}
+
+	var (
+		dirs   []directory
+		assets []asset
+		base   = flagOptions()
+	)
+
+	for n, entry := range m.Assets {
+		if entry.Input == "" {
+			e(fmt.Errorf("manifest entry %d: missing input", n+1))
+		} else if entry.Path == "" && !entry.Recursive {
+			e(fmt.Errorf("manifest entry %d: missing path", n+1))
+		}
+
+		o, err := entry.options(base)
+		e(err)
+
+		input := manifestPath(filename, entry.Input)
+
+		if entry.Recursive {
+			ds, as := readDir(input, entry.Path, o)
+			dirs = append(dirs, ds...)
+			assets = append(assets, as...)
+		} else {
+			assets = append(assets, readFile(input, entry.Path, o))
+		}
+	}
+
+	return dirs, assets
+}
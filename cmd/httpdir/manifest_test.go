@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestEntryOptions(t *testing.T) {
+	date := int64(1)
+	base := options{
+		Gzip:      true,
+		Brotli:    true,
+		Index:     true,
+		ChunkSize: 1 << 20,
+		Include:   []string{"*.js"},
+		Var:       "httpdir.Default",
+		CVar:      "httpdir.Default",
+		Date:      &date,
+	}
+
+	for n, test := range [...]struct {
+		Entry   string
+		Options func(o *options)
+		Err     string
+	}{
+		{ // 1
+			Entry:   `{}`,
+			Options: func(*options) {},
+		},
+		{ // 2
+			Entry: `{"encodings": ["zstd", "zopfli"]}`,
+			Options: func(o *options) {
+				o.Gzip, o.Brotli, o.Zstd, o.Zopfli = false, false, true, true
+			},
+		},
+		{ // 3
+			Entry: `{"encodings": []}`,
+			Options: func(o *options) {
+				o.Gzip, o.Brotli = false, false
+			},
+		},
+		{ // 4
+			Entry: `{"single": true, "pureGoBrotli": true, "var": "a", "compressedVar": "b", "date": 2}`,
+			Options: func(o *options) {
+				d := int64(2)
+				o.Single, o.PureGoBrotli, o.Var, o.CVar, o.Date = true, true, "a", "b", &d
+			},
+		},
+		{ // 5
+			Entry: `{"index": false, "skipCompressedTypes": true, "minSavingsBytes": 0, "minSavingsRatio": 0.1}`,
+			Options: func(o *options) {
+				o.Index, o.SkipCompressedTypes, o.MinSavingsRatio = false, true, 0.1
+			},
+		},
+		{ // 6
+			Entry: `{"minify": true, "minifyType": "css", "fingerprint": true, "chunkSize": 0}`,
+			Options: func(o *options) {
+				o.Minify, o.MinifyType, o.Fingerprint, o.ChunkSize = true, "css", true, 0
+			},
+		},
+		{ // 7
+			Entry: `{"include": [], "exclude": ["*.map"], "ignore": true}`,
+			Options: func(o *options) {
+				o.Include, o.Exclude, o.Ignore = []string{}, []string{"*.map"}, true
+			},
+		},
+		{ // 8
+			Entry: `{"input": "a.txt", "encodings": ["lzma"]}`,
+			Err:   `a.txt: unknown encoding: "lzma"`,
+		},
+		{ // 9
+			Entry: `{"input": "a.txt", "minifyType": "xml"}`,
+			Err:   `a.txt: unknown minifier: "xml"`,
+		},
+	} {
+		var entry manifestEntry
+
+		if err := json.Unmarshal([]byte(test.Entry), &entry); err != nil {
+			t.Fatalf("test %d: %s", n+1, err)
+		}
+
+		o, err := entry.options(base)
+		if test.Err != "" {
+			if err == nil || err.Error() != test.Err {
+				t.Errorf("test %d: expecting error %q, got %v", n+1, test.Err, err)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+
+			continue
+		}
+
+		expected := base
+		expected.Include = append([]string(nil), base.Include...)
+
+		test.Options(&expected)
+
+		if !reflect.DeepEqual(o, expected) {
+			t.Errorf("test %d: expecting options %+v, got %+v", n+1, expected, o)
+		}
+	}
+
+	if *base.Date != 1 || len(base.Include) != 1 {
+		t.Errorf("base options were modified: %+v", base)
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("a", "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, test := range [...]struct {
+		Manifest, Name, Path string
+	}{
+		{Manifest: "manifest.json", Name: "a.txt", Path: "a.txt"},
+		{Manifest: filepath.Join("assets", "manifest.json"), Name: "a.txt", Path: filepath.Join("assets", "a.txt")},
+		{Manifest: filepath.Join("assets", "manifest.json"), Name: filepath.Join("..", "a.txt"), Path: "a.txt"},
+		{Manifest: filepath.Join("assets", "manifest.json"), Name: abs, Path: abs},
+	} {
+		if path := manifestPath(test.Manifest, test.Name); path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Path, path)
+		}
+	}
+}
+
+func TestManifestGenerate(t *testing.T) {
+	generator, run := buildGenerator(t)
+	tmp := t.TempDir()
+	pkgDir := generatedDir(t)
+	pkg := "./" + filepath.Base(pkgDir)
+	manifest := filepath.Join(tmp, "manifest.json")
+
+	output, err := filepath.Abs(filepath.Join(pkgDir, "assets.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTree(t, filepath.Join(tmp, "site"), testSite)
+	writeTree(t, tmp, map[string]string{
+		"manifest.json": fmt.Sprintf(`{
+	"package": "assets",
+	"output": %q,
+	"register": "Register",
+	"new": "New",
+	"test": true,
+	"dev": true,
+	"assets": [
+		{"input": "site", "path": "/", "recursive": true, "encodings": ["gzip", "zstd"], "date": 1},
+		{"input": "site/js/app.js", "path": "/app.js", "encodings": ["brotli"], "pureGoBrotli": true, "single": true, "date": 1}
+	]
+}`, output),
+	})
+
+	generate := func(args ...string) (string, error) {
+		out, err := exec.Command(generator, append([]string{"-m", manifest, "-p", "flagpkg", "-e", "data"}, args...)...).CombinedOutput()
+
+		return string(out), err
+	}
+
+	if out, err := generate(); err != nil {
+		t.Fatalf("generator: %s\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range [...]string{"package flagpkg\n", "func Register(", "func New(", "//go:embed data/"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("expecting generated code to contain %q", want)
+		}
+	}
+
+	run("vet", pkg)
+	run("test", pkg)
+	run("vet", "-tags", "dev", pkg)
+
+	if out, err := generate("-check"); err != nil {
+		t.Fatalf("expecting up to date output, got %s\n%s", err, out)
+	}
+
+	embedded := filepath.Join(pkgDir, "data", "app.js.br")
+
+	original, err := os.ReadFile(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, test := range [...]struct {
+		Name, Contents, Message string
+	}{
+		{Name: embedded, Contents: "stale", Message: embedded + " is out of date"},
+		{Name: filepath.Join(pkgDir, "data", "old.js"), Message: filepath.Join(pkgDir, "data", "old.js") + " is not part of the generated output"},
+	} {
+		if err := os.WriteFile(test.Name, []byte(test.Contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if out, err := generate("-check"); err == nil {
+			t.Errorf("test %d: expecting check to fail", n+1)
+		} else if !strings.Contains(out, test.Message) {
+			t.Errorf("test %d: expecting message %q, got %q", n+1, test.Message, out)
+		}
+
+		if test.Name == embedded {
+			err = os.WriteFile(embedded, original, 0o644)
+		} else {
+			err = os.Remove(test.Name)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	errUnterminated = errors.New("unterminated token")
+	errPreserve     = errors.New("whitespace preservation requested")
+	errAmbiguous    = errors.New("ambiguous regular expression or division")
+)
+
+type minifier func([]byte) ([]byte, error)
+
+var minifiers = map[string]minifier{
+	"css":  minifyCSS,
+	"html": minifyHTML,
+	"js":   minifyJS,
+	"json": minifyJSON,
+	"svg":  minifySVG,
+}
+
+var minifyExts = map[string]string{
+	".css":  "css",
+	".htm":  "html",
+	".html": "html",
+	".js":   "js",
+	".mjs":  "js",
+	".json": "json",
+	".svg":  "svg",
+}
+
+// minify minifies the data, using either the given minifier type or, when
+// typ is empty, one selected by the extension of name. If the data cannot be
+// minified, it is returned unaltered.
+func minify(data []byte, name, typ string) []byte {
+	if typ == "" {
+		typ = minifyExts[strings.ToLower(path.Ext(name))]
+	}
+
+	m, ok := minifiers[typ]
+	if !ok {
+		return data
+	}
+
+	out, err := m(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: not minified: %s\n", name, err)
+
+		return data
+	}
+
+	if len(out) >= len(data) {
+		return data
+	}
+
+	return out
+}
+
+func minifyJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// scanString returns the position after the closing quote of the string
+// starting at data[start].
+func scanString(data []byte, start int) (int, error) {
+	quote := data[start]
+
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case quote:
+			return i + 1, nil
+		case '\\':
+			i++
+		case '\n':
+			return 0, errUnterminated
+		}
+	}
+
+	return 0, errUnterminated
+}
+
+const cssSeparators = "{};,>"
+
+func minifyCSS(data []byte) ([]byte, error) {
+	var (
+		out            = make([]byte, 0, len(data))
+		space, comment bool
+	)
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		switch {
+		case c == '"' || c == '\'':
+			end, err := scanString(data, i)
+			if err != nil {
+				return nil, err
+			}
+
+			if space && cssSpace(out, c) {
+				out = append(out, ' ')
+			}
+
+			out = append(out, data[i:end]...)
+			space, comment = false, false
+			i = end
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, errUnterminated
+			}
+
+			end += i + 4
+
+			if i+2 < len(data) && data[i+2] == '!' {
+				out = append(out, data[i:end]...)
+			} else {
+				comment = true
+			}
+
+			i = end
+		case isSpace(c):
+			space = true
+			i++
+		default:
+			if space && cssSpace(out, c) || comment && cssJoins(out, c) {
+				out = append(out, ' ')
+			}
+
+			if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+				out = out[:len(out)-1]
+			}
+
+			out = append(out, c)
+			space, comment = false, false
+			i++
+		}
+	}
+
+	return out, nil
+}
+
+// cssSpace determines whether whitespace between the already written output
+// and the next character needs to be retained.
+func cssSpace(out []byte, next byte) bool {
+	if len(out) == 0 || bytes.HasSuffix(out, []byte("*/")) {
+		return false
+	}
+
+	return !strings.ContainsRune(cssSeparators, rune(out[len(out)-1])) && !strings.ContainsRune(cssSeparators, rune(next))
+}
+
+// cssJoins determines whether removing a comment between the already written
+// output and the next character would join two tokens into one, such as the
+// lengths in "1px/**/2px".
+func cssJoins(out []byte, next byte) bool {
+	return len(out) > 0 && cssName(out[len(out)-1]) && cssName(next)
+}
+
+func cssName(c byte) bool {
+	return isIdent(c) || c == '-'
+}
+
+const jsSafe = "{}()[];,:=*%^~&|"
+
+var regexpKeywords = map[string]bool{
+	"await":      true,
+	"case":       true,
+	"delete":     true,
+	"do":         true,
+	"else":       true,
+	"in":         true,
+	"instanceof": true,
+	"new":        true,
+	"of":         true,
+	"return":     true,
+	"throw":      true,
+	"typeof":     true,
+	"void":       true,
+	"yield":      true,
+}
+
+func isIdent(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// paren records what precedes an opening parenthesis, which determines
+// whether a '/' following the matching closing parenthesis starts a regular
+// expression, as after the condition of an if statement, or is a division.
+type paren uint8
+
+const (
+	parenExpr paren = iota
+	parenCond
+	parenUnknown
+)
+
+var condKeywords = map[string]bool{
+	"for":   true,
+	"if":    true,
+	"while": true,
+	"with":  true,
+}
+
+func lastIdent(out []byte) string {
+	start := len(out)
+
+	for start > 0 && isIdent(out[start-1]) {
+		start--
+	}
+
+	return string(out[start:])
+}
+
+// lastKeyword returns the identifier at the end of the output, unless it is
+// a property name, such as in "a.return", which cannot be a keyword.
+func lastKeyword(out []byte) string {
+	word := lastIdent(out)
+
+	if start := len(out) - len(word); start > 0 && out[start-1] == '.' {
+		return ""
+	}
+
+	return word
+}
+
+func openParen(out []byte) paren {
+	switch word := lastKeyword(out); {
+	case condKeywords[word]:
+		return parenCond
+	case word == "await":
+		return parenUnknown
+	}
+
+	return parenExpr
+}
+
+func regexpAllowed(out []byte) bool {
+	if len(out) == 0 {
+		return true
+	}
+
+	last := out[len(out)-1]
+
+	if isIdent(last) {
+		return regexpKeywords[lastKeyword(out)]
+	}
+
+	return last != ')' && last != ']'
+}
+
+func scanRegexp(data []byte, start int) (int, error) {
+	class := false
+
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				for i++; i < len(data) && isIdent(data[i]); i++ {
+				}
+
+				return i, nil
+			}
+		case '\n':
+			return 0, errUnterminated
+		}
+	}
+
+	return 0, errUnterminated
+}
+
+// scanTemplate returns the position after the closing backtick of the
+// template literal starting at data[start], skipping over any substitutions.
+func scanTemplate(data []byte, start int) (int, error) {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, nil
+		case '$':
+			if i+1 < len(data) && data[i+1] == '{' {
+				end, err := scanSubstitution(data, i+2)
+				if err != nil {
+					return 0, err
+				}
+
+				i = end - 1
+			}
+		}
+	}
+
+	return 0, errUnterminated
+}
+
+func scanSubstitution(data []byte, start int) (int, error) {
+	depth := 1
+
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '"', '\'':
+			end, err := scanString(data, i)
+			if err != nil {
+				return 0, err
+			}
+
+			i = end - 1
+		case '`':
+			end, err := scanTemplate(data, i)
+			if err != nil {
+				return 0, err
+			}
+
+			i = end - 1
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+
+	return 0, errUnterminated
+}
+
+// minifyJS removes comments, indentation and blank lines, and collapses
+// whitespace where it is safe to do so. Line breaks are retained so that
+// automatic semicolon insertion is unaffected.
+func minifyJS(data []byte) ([]byte, error) {
+	var (
+		out            = make([]byte, 0, len(data))
+		space, newline bool
+		parens         []paren
+		closed         = parenUnknown
+	)
+
+	emit := func(c byte) {
+		if len(out) > 0 {
+			if newline {
+				out = append(out, '\n')
+			} else if space && !strings.ContainsRune(jsSafe, rune(out[len(out)-1])) && !strings.ContainsRune(jsSafe, rune(c)) {
+				out = append(out, ' ')
+			}
+		}
+
+		space, newline = false, false
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		var (
+			end int
+			err error
+		)
+
+		switch {
+		case c == '\n':
+			newline = true
+			i++
+
+			continue
+		case isSpace(c):
+			space = true
+			i++
+
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i += 2; i < len(data) && data[i] != '\n'; i++ {
+			}
+
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			e := bytes.Index(data[i+2:], []byte("*/"))
+			if e < 0 {
+				return nil, errUnterminated
+			}
+
+			end = i + e + 4
+
+			if data[i+2] != '!' {
+				if bytes.IndexByte(data[i:end], '\n') >= 0 {
+					newline = true
+				} else {
+					space = true
+				}
+
+				i = end
+
+				continue
+			}
+		case c == '"' || c == '\'':
+			end, err = scanString(data, i)
+		case c == '`':
+			end, err = scanTemplate(data, i)
+		case c == '/' && len(out) > 0 && out[len(out)-1] == ')':
+			switch closed {
+			case parenCond:
+				end, err = scanRegexp(data, i)
+			case parenExpr:
+				end = i + 1
+			default:
+				err = errAmbiguous
+			}
+		case c == '/' && len(out) > 0 && out[len(out)-1] == '}':
+			// a '}' may close either a block, after which a '/'
+			// starts a regular expression, or an object literal or
+			// function expression, after which it is a division.
+			err = errAmbiguous
+		case c == '/' && regexpAllowed(out):
+			end, err = scanRegexp(data, i)
+		case c == '(':
+			parens = append(parens, openParen(out))
+			end = i + 1
+		case c == ')':
+			closed = parenUnknown
+
+			if len(parens) > 0 {
+				closed = parens[len(parens)-1]
+				parens = parens[:len(parens)-1]
+			}
+
+			end = i + 1
+		default:
+			end = i + 1
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		emit(c)
+
+		out = append(out, data[i:end]...)
+		i = end
+	}
+
+	return out, nil
+}
+
+var (
+	htmlRawElements = []string{"pre", "script", "style", "textarea"}
+	svgRawElements  = []string{"script", "style"}
+)
+
+func minifyHTML(data []byte) ([]byte, error) {
+	return minifyMarkup(data, htmlRawElements)
+}
+
+func minifySVG(data []byte) ([]byte, error) {
+	if bytes.Contains(data, []byte("xml:space")) {
+		return nil, errPreserve
+	}
+
+	return minifyMarkup(data, svgRawElements)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// minifyMarkup removes comments, other than conditional comments, and
+// collapses runs of whitespace in text and tags into a single space, or a
+// single line break when the run contains one. The contents of the given raw
+// elements are left unaltered.
+func minifyMarkup(data []byte, raw []string) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		switch {
+		case isSpace(c):
+			end := i
+
+			for end < len(data) && isSpace(data[end]) {
+				end++
+			}
+
+			ws := byte(' ')
+
+			if bytes.IndexByte(data[i:end], '\n') >= 0 {
+				ws = '\n'
+			}
+
+			if last := len(out) - 1; last >= 0 && (out[last] == ' ' || out[last] == '\n') {
+				if ws == '\n' {
+					out[last] = ws
+				}
+			} else {
+				out = append(out, ws)
+			}
+
+			i = end
+		case bytes.HasPrefix(data[i:], []byte("<!--")):
+			end := bytes.Index(data[i+4:], []byte("-->"))
+			if end < 0 {
+				return nil, errUnterminated
+			}
+
+			end += i + 7
+
+			if bytes.HasPrefix(data[i+4:], []byte("[")) {
+				out = append(out, data[i:end]...)
+			}
+
+			i = end
+		case bytes.HasPrefix(data[i:], []byte("<![CDATA[")):
+			end := bytes.Index(data[i:], []byte("]]>"))
+			if end < 0 {
+				return nil, errUnterminated
+			}
+
+			out = append(out, data[i:i+end+3]...)
+			i += end + 3
+		case c == '<' && i+1 < len(data) && (data[i+1] == '!' || data[i+1] == '?'):
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return nil, errUnterminated
+			}
+
+			out = append(out, data[i:i+end+1]...)
+			i += end + 1
+		case c == '<' && i+1 < len(data) && (isLetter(data[i+1]) || data[i+1] == '/'):
+			end, err := copyTag(&out, data, i)
+			if err != nil {
+				return nil, err
+			}
+
+			name := i + 1
+			nameEnd := name
+
+			for nameEnd < end && (isLetter(data[nameEnd]) || data[nameEnd] >= '0' && data[nameEnd] <= '9' || data[nameEnd] == '-' || data[nameEnd] == ':') {
+				nameEnd++
+			}
+
+			tag := strings.ToLower(string(data[name:nameEnd]))
+			i = end
+
+			if data[end-2] == '/' {
+				break
+			}
+
+			for _, r := range raw {
+				if tag == r {
+					close := indexFold(data[i:], "</"+tag)
+					if close < 0 {
+						return nil, errUnterminated
+					}
+
+					out = append(out, data[i:i+close]...)
+					i += close
+
+					break
+				}
+			}
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+
+	return out, nil
+}
+
+// copyTag copies the tag starting at data[start] to out, collapsing
+// whitespace outside of quoted attribute values, and returns the position
+// after the end of the tag.
+func copyTag(out *[]byte, data []byte, start int) (int, error) {
+	for i := start; i < len(data); {
+		switch c := data[i]; {
+		case c == '>':
+			*out = append(*out, c)
+
+			return i + 1, nil
+		case c == '"' || c == '\'':
+			end := bytes.IndexByte(data[i+1:], c)
+			if end < 0 {
+				return 0, errUnterminated
+			}
+
+			*out = append(*out, data[i:i+end+2]...)
+			i += end + 2
+		case isSpace(c):
+			for i < len(data) && isSpace(data[i]) {
+				i++
+			}
+
+			*out = append(*out, ' ')
+		default:
+			*out = append(*out, c)
+			i++
+		}
+	}
+
+	return 0, errUnterminated
+}
+
+func indexFold(data []byte, substr string) int {
+	for i := 0; i+len(substr) <= len(data); i++ {
+		if strings.EqualFold(string(data[i:i+len(substr)]), substr) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	for n, test := range [...]struct {
+		Name, Type, Input, Output string
+	}{
+		{ // 1
+			Name:   "a.json",
+			Input:  "{\n\t\"a\": [1, 2, \"b c\"],\n\t\"d\": {}\n}\n",
+			Output: `{"a":[1,2,"b c"],"d":{}}`,
+		},
+		{ // 2
+			Name:   "a.json",
+			Input:  "{\n\t\"a\": [1, 2,\n}\n",
+			Output: "{\n\t\"a\": [1, 2,\n}\n",
+		},
+		{ // 3
+			Name:   "a.css",
+			Input:  "/* comment */\nbody {\n\tmargin: 0;\n\tfont-family: \"A  B\", sans-serif;\n}\n\na > b,\nc:hover {\n\twidth: calc(1px + 2px);\n}\n",
+			Output: "body{margin: 0;font-family: \"A  B\",sans-serif}a>b,c:hover{width: calc(1px + 2px)}",
+		},
+		{ // 4
+			Name:   "a.css",
+			Input:  "/*! licence */\na  :hover { color: red }",
+			Output: "/*! licence */a :hover{color: red}",
+		},
+		{ // 5
+			Name:   "a.css",
+			Input:  "a { content: \"unterminated }",
+			Output: "a { content: \"unterminated }",
+		},
+		{ // 6
+			Name:   "a.css",
+			Input:  "a { color: red } /* unterminated",
+			Output: "a { color: red } /* unterminated",
+		},
+		{ // 7
+			Name:   "a.js",
+			Input:  "// comment\nfunction a(b, c) {\n\treturn b + +c; // add\n}\n\n\n/* block */ var d = a(1, 2);\n",
+			Output: "function a(b,c){\nreturn b + +c;\n}\nvar d=a(1,2);",
+		},
+		{ // 8
+			Name:   "a.js",
+			Input:  "var a = \"  // not a comment  \", b = '/* nor this */';\n",
+			Output: "var a=\"  // not a comment  \",b='/* nor this */';",
+		},
+		{ // 9
+			Name:   "a.js",
+			Input:  "var re = /[/]  \\/ '/g, c = a / b / 2;\n",
+			Output: "var re=/[/]  \\/ '/g,c=a / b / 2;",
+		},
+		{ // 10
+			Name:   "a.js",
+			Input:  "var t = `a  ${ {b: `c  ${d}`}.b }  // e`;\n",
+			Output: "var t=`a  ${ {b: `c  ${d}`}.b }  // e`;",
+		},
+		{ // 11
+			Name:   "a.js",
+			Input:  "a = b\n/* multi\nline */ ++c\n",
+			Output: "a=b\n++c",
+		},
+		{ // 12
+			Name:   "a.js",
+			Input:  "var a = 'unterminated\n';",
+			Output: "var a = 'unterminated\n';",
+		},
+		{ // 13
+			Name:   "a.js",
+			Input:  "/*! licence */\nreturn  /a b/",
+			Output: "/*! licence */\nreturn /a b/",
+		},
+		{ // 14
+			Name:   "index.html",
+			Input:  "<!DOCTYPE html>\n<html>\n\t<!-- comment -->\n\t<body  class=\"a  b\">\n\t\t<b>a</b>  <i>b</i>\n\t\t<pre>  keep\n  this</pre>\n\t\t<script>\n\t\t\tvar a = \"<b>\";\n\t\t</script>\n\t\t<!--[if IE]>ie<![endif]-->\n\t</body>\n</html>\n",
+			Output: "<!DOCTYPE html>\n<html>\n<body class=\"a  b\">\n<b>a</b> <i>b</i>\n<pre>  keep\n  this</pre>\n<script>\n\t\t\tvar a = \"<b>\";\n\t\t</script>\n<!--[if IE]>ie<![endif]-->\n</body>\n</html>\n",
+		},
+		{ // 15
+			Name:   "index.html",
+			Input:  "<p>a <  b</p>  <br />\n",
+			Output: "<p>a < b</p> <br />\n",
+		},
+		{ // 16
+			Name:   "index.html",
+			Input:  "<p>a</p>\n<!-- unterminated",
+			Output: "<p>a</p>\n<!-- unterminated",
+		},
+		{ // 17
+			Name:   "index.html",
+			Input:  "<p>a</p>  <textarea>  b",
+			Output: "<p>a</p>  <textarea>  b",
+		},
+		{ // 18
+			Name:   "image.svg",
+			Input:  "<?xml version=\"1.0\"?>\n<!-- comment -->\n<svg  xmlns=\"http://www.w3.org/2000/svg\">\n\t<style><![CDATA[ a  >  b {} ]]></style>\n\t<text>a  b</text>\n</svg>\n",
+			Output: "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\">\n<style><![CDATA[ a  >  b {} ]]></style>\n<text>a b</text>\n</svg>\n",
+		},
+		{ // 19
+			Name:   "image.svg",
+			Input:  "<svg>\n\t<text xml:space=\"preserve\">a  b</text>\n</svg>\n",
+			Output: "<svg>\n\t<text xml:space=\"preserve\">a  b</text>\n</svg>\n",
+		},
+		{ // 20
+			Name:   "data.txt",
+			Input:  "{\n\t\"a\": 1\n}\n",
+			Output: "{\n\t\"a\": 1\n}\n",
+		},
+		{ // 21
+			Name:   "data.txt",
+			Type:   "json",
+			Input:  "{\n\t\"a\": 1\n}\n",
+			Output: `{"a":1}`,
+		},
+		{ // 22
+			Name:   "STYLE.CSS",
+			Input:  "a {\n\tcolor: red;\n}\n",
+			Output: "a{color: red}",
+		},
+		{ // 23
+			Name:   "a.js",
+			Input:  "if (x) /a  b/.test(y);\n",
+			Output: "if(x)/a  b/.test(y);",
+		},
+		{ // 24
+			Name:   "a.js",
+			Input:  "var a = (b + c) / 2, d = f(g) / h(i);\n",
+			Output: "var a=(b + c)/ 2,d=f(g)/ h(i);",
+		},
+		{ // 25
+			Name:   "a.js",
+			Input:  "while (f(a)) /a  b/.exec(c);\n",
+			Output: "while(f(a))/a  b/.exec(c);",
+		},
+		{ // 26
+			Name:   "a.js",
+			Input:  "a)  /b  c/.test(d);\n",
+			Output: "a)  /b  c/.test(d);\n",
+		},
+		{ // 27
+			Name:   "a.css",
+			Input:  ".a/**/.b, .c /* d */ .e, f/**/g {\n\tmargin: 1px/**/-2px;\n}\n",
+			Output: ".a.b,.c .e,f g{margin: 1px -2px}",
+		},
+		{ // 28
+			Name:   "a.js",
+			Input:  "var a = b.return / 2 / c, d = e.if(f) / 2;\n",
+			Output: "var a=b.return / 2 / c,d=e.if(f)/ 2;",
+		},
+	} {
+		if out := string(minify([]byte(test.Input), test.Name, test.Type)); out != test.Output {
+			t.Errorf("test %d: expecting output %q, got %q", n+1, test.Output, out)
+		}
+	}
+}
+
+func TestMinifyErrors(t *testing.T) {
+	for n, test := range [...]struct {
+		Minifier minifier
+		Input    string
+		Err      error
+	}{
+		{Minifier: minifyCSS, Input: "a { b: 'c", Err: errUnterminated},
+		{Minifier: minifyJS, Input: "a = /b", Err: errUnterminated},
+		{Minifier: minifyJS, Input: "a = `${b`", Err: errUnterminated},
+		{Minifier: minifyJS, Input: "a) / b", Err: errAmbiguous},
+		{Minifier: minifyJS, Input: "for await (a of b) /c/.test(a)", Err: errAmbiguous},
+		{Minifier: minifyJS, Input: "if (a) {}\n/b/.test(c)", Err: errAmbiguous},
+		{Minifier: minifyJS, Input: "a = function() {} / b", Err: errAmbiguous},
+		{Minifier: minifyHTML, Input: "<a href=\"b>", Err: errUnterminated},
+		{Minifier: minifyHTML, Input: "<style>a {}</styl", Err: errUnterminated},
+		{Minifier: minifySVG, Input: "<svg><![CDATA[</svg>", Err: errUnterminated},
+		{Minifier: minifySVG, Input: "<svg xml:space=\"preserve\"/>", Err: errPreserve},
+	} {
+		if _, err := test.Minifier([]byte(test.Input)); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+}
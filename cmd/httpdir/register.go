@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+)
+
+// registerName returns the name of the function that adds the asset at the
+// given web path, or the directories when the path is empty, in register mode.
+func registerName(webPath string) string {
+	return "httpdirRegister_" + hash(*registerFn+webPath)
+}
+
+// setRegisterVars validates the register mode flags and, in register mode,
+// sets all assets and directories to be created in the Dir passed to the
+// generated function.
+func setRegisterVars(dirs []directory, assets []asset) {
+	if *registerFn == "" {
+		if *newFn != "" {
+			e(errors.New("-new requires -register"))
+		}
+
+		return
+	}
+
+	for _, name := range [...]string{*registerFn, *newFn} {
+		if name != "" && !token.IsIdentifier(name) {
+			e(fmt.Errorf("invalid function name: %q", name))
+		}
+	}
+
+	for n := range dirs {
+		dirs[n].Vars = []string{"d"}
+	}
+
+	for n := range assets {
+		assets[n].Opts.Var = "d"
+		assets[n].Opts.CVar = "d"
+	}
+}
+
+func writeRegister(w io.Writer, dirs []directory, assets []asset) {
+	var date int64
+
+	ne(fmt.Fprintf(w, registerFuncStart, *registerFn))
+
+	if len(dirs) > 0 {
+		ne(fmt.Fprintf(w, registerFuncEntry, registerName("")))
+	}
+
+	for _, d := range dirs {
+		if d.Date > date {
+			date = d.Date
+		}
+	}
+
+	for _, a := range assets {
+		ne(fmt.Fprintf(w, registerFuncEntry, registerName(a.Path)))
+
+		if a.Date > date {
+			date = a.Date
+		}
+	}
+
+	ne(io.WriteString(w, registerFuncEnd))
+
+	if *newFn != "" {
+		ne(fmt.Fprintf(w, newFunc, *newFn, *registerFn, date))
+	}
+}
+
+const (
+	registerFuncStart = `
+// %s adds the generated files and directories to the given Dir.
+func %[1]s(d httpdir.Dir) error {
+	for _, fn := range [...]func(httpdir.Dir) error{
+`
+	registerFuncEntry = `		%s,
+`
+	registerFuncEnd = `	} {
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+`
+	newFunc = `
+// %s returns a new Dir containing the generated files and directories.
+func %[1]s() httpdir.Dir {
+	d := httpdir.New(time.Unix(%[3]d, 0))
+
+	if err := %[2]s(d); err != nil {
+		panic(err)
+	}
+
+	return d
+}
+`
+)
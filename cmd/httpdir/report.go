@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var compressTime time.Duration
+
+var encodingNames = map[string]string{
+	"":     "identity",
+	".br":  "brotli",
+	".fl":  "flate",
+	".gz":  "gzip",
+	".zst": "zstd",
+}
+
+type reportEncoding struct {
+	Encoding string  `json:"encoding"`
+	Size     int     `json:"size"`
+	Ratio    float64 `json:"ratio"`
+	Dropped  string  `json:"dropped,omitempty"`
+}
+
+type reportAsset struct {
+	Path      string           `json:"path"`
+	Size      int              `json:"size"`
+	Embedded  int              `json:"embedded"`
+	Source    string           `json:"source,omitempty"`
+	Encodings []reportEncoding `json:"encodings"`
+}
+
+type report struct {
+	Assets          []reportAsset `json:"assets"`
+	Size            int           `json:"size"`
+	Embedded        int           `json:"embedded"`
+	CompressSeconds float64       `json:"compressSeconds"`
+}
+
+func encodingName(a asset, ext string) string {
+	if ext == ".gz" && a.Opts.Zopfli {
+		return "zopfli"
+	}
+
+	return encodingNames[ext]
+}
+
+func ratio(size, original int) float64 {
+	if original == 0 {
+		return 1
+	}
+
+	return float64(size) / float64(original)
+}
+
+// newReport collects the sizes of each asset and its variants, including
+// those that were dropped. The embedded size of an asset is the number of
+// bytes of data it adds to the generated code.
+func newReport(assets []asset) report {
+	r := report{
+		Assets:          make([]reportAsset, 0, len(assets)),
+		CompressSeconds: compressTime.Seconds(),
+	}
+
+	for _, a := range assets {
+		ra := reportAsset{
+			Path:      a.Path,
+			Size:      a.Size,
+			Encodings: make([]reportEncoding, 0, len(a.Encs)),
+		}
+
+		for _, enc := range a.Encs {
+			ra.Encodings = append(ra.Encodings, reportEncoding{
+				Encoding: encodingName(a, enc.Ext),
+				Size:     len(enc.Buffer),
+				Ratio:    ratio(len(enc.Buffer), a.Size),
+			})
+
+			if !a.single() {
+				ra.Embedded += len(enc.Buffer)
+			}
+		}
+
+		if a.single() {
+			ra.Source = encodingName(a, a.Encs[0].Ext)
+			ra.Embedded = len(a.Encs[0].Buffer)
+		}
+
+		for _, d := range dropped {
+			if d.Path == a.Path {
+				ra.Encodings = append(ra.Encodings, reportEncoding{
+					Encoding: encodingName(a, d.Ext),
+					Size:     d.Size,
+					Ratio:    ratio(d.Size, d.Original),
+					Dropped:  d.Reason,
+				})
+			}
+		}
+
+		r.Assets = append(r.Assets, ra)
+		r.Size += ra.Size
+		r.Embedded += ra.Embedded
+	}
+
+	return r
+}
+
+func (r report) writeJSON(w io.Writer) {
+	enc := json.NewEncoder(w)
+
+	enc.SetIndent("", "\t")
+	e(enc.Encode(r))
+}
+
+func (r report) writeTable(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	ne(io.WriteString(tw, "PATH\tSIZE\tEMBEDDED\tENCODING\tSIZE\tRATIO\t\n"))
+
+	for _, a := range r.Assets {
+		for n, enc := range a.Encodings {
+			path, size, embedded := "", "", ""
+
+			if n == 0 {
+				path, size, embedded = a.Path, fmt.Sprint(a.Size), fmt.Sprint(a.Embedded)
+			}
+
+			name := enc.Encoding
+
+			if enc.Dropped != "" {
+				name += " (dropped: " + enc.Dropped + ")"
+			} else if enc.Encoding == a.Source {
+				name += " (source)"
+			}
+
+			ne(fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%.3f\t\n", path, size, embedded, name, enc.Size, enc.Ratio))
+		}
+	}
+
+	ne(fmt.Fprintf(tw, "total\t%d\t%d\t\t\t%.3f\t\n", r.Size, r.Embedded, ratio(r.Embedded, r.Size)))
+	e(tw.Flush())
+	ne(fmt.Fprintf(w, "compression took %.3fs\n", r.CompressSeconds))
+}
+
+func writeReport(filename, format string, assets []asset) {
+	var (
+		f   *os.File
+		err error
+	)
+
+	if format != "json" && format != "table" {
+		e(fmt.Errorf("unknown report format: %q", format))
+	}
+
+	r := newReport(assets)
+
+	if filename == "-" {
+		f = os.Stdout
+	} else {
+		f, err = os.Create(filename)
+		e(err)
+	}
+
+	if format == "json" {
+		r.writeJSON(f)
+	} else {
+		r.writeTable(f)
+	}
+
+	if f != os.Stdout {
+		e(f.Close())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	dropped = []droppedVariant{{Path: "/b.txt", Ext: ".gz", Reason: "insufficient savings", Size: 30, Original: 20}}
+
+	defer func() { dropped = nil }()
+
+	r := newReport([]asset{
+		{
+			Path: "/a.txt",
+			Size: 100,
+			Encs: encodings{{Buffer: make([]byte, 25), Ext: ".br"}, {Buffer: make([]byte, 40), Ext: ".gz"}, {Buffer: make([]byte, 100)}},
+			Opts: options{Single: true, Zopfli: true},
+		},
+		{
+			Path: "/b.txt",
+			Size: 20,
+			Encs: encodings{{Buffer: make([]byte, 20)}},
+		},
+		{
+			Path: "/c.txt",
+			Size: 100,
+			Encs: encodings{{Buffer: make([]byte, 50), Ext: ".zst"}, {Buffer: make([]byte, 100)}},
+		},
+	})
+
+	expected := []reportAsset{
+		{
+			Path:     "/a.txt",
+			Size:     100,
+			Embedded: 25,
+			Source:   "brotli",
+			Encodings: []reportEncoding{
+				{Encoding: "brotli", Size: 25, Ratio: 0.25},
+				{Encoding: "zopfli", Size: 40, Ratio: 0.4},
+				{Encoding: "identity", Size: 100, Ratio: 1},
+			},
+		},
+		{
+			Path:     "/b.txt",
+			Size:     20,
+			Embedded: 20,
+			Encodings: []reportEncoding{
+				{Encoding: "identity", Size: 20, Ratio: 1},
+				{Encoding: "gzip", Size: 30, Ratio: 1.5, Dropped: "insufficient savings"},
+			},
+		},
+		{
+			Path:     "/c.txt",
+			Size:     100,
+			Embedded: 150,
+			Encodings: []reportEncoding{
+				{Encoding: "zstd", Size: 50, Ratio: 0.5},
+				{Encoding: "identity", Size: 100, Ratio: 1},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(r.Assets, expected) {
+		t.Errorf("expecting assets %v, got %v", expected, r.Assets)
+	}
+
+	if r.Size != 220 {
+		t.Errorf("expecting size 220, got %d", r.Size)
+	}
+
+	if r.Embedded != 195 {
+		t.Errorf("expecting embedded size 195, got %d", r.Embedded)
+	}
+}
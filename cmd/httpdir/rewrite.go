@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"strings"
+)
+
+type source struct {
+	Path string
+	File string
+	Date int64
+	Data []byte
+}
+
+type reference struct {
+	Start, End int
+	Target     string
+}
+
+// rewriteAssets creates assets from the given sources, rewriting references in
+// HTML and CSS files to the fingerprinted paths of the other sources.
+//
+// The sources are processed in dependency order, so that the fingerprint of
+// each file is calculated after its own references have been rewritten. A
+// cycle of references between fingerprinted files is an error.
+func rewriteAssets(sources []source, o options) []asset {
+	byPath := make(map[string]int, len(sources))
+
+	for n, s := range sources {
+		byPath[s.Path] = n
+	}
+
+	refs := make([][]reference, len(sources))
+
+	for n, s := range sources {
+		for _, ref := range references(s.Path, s.Data) {
+			if _, ok := byPath[ref.Target]; ok && ref.Target != s.Path && fingerprinted(ref.Target) {
+				refs[n] = append(refs[n], ref)
+			}
+		}
+	}
+
+	var (
+		order []int
+		state = make([]byte, len(sources))
+		visit func(int)
+	)
+
+	visit = func(n int) {
+		switch state[n] {
+		case 1:
+			e(fmt.Errorf("%s: reference cycle", sources[n].Path))
+		case 2:
+			return
+		}
+
+		state[n] = 1
+
+		for _, ref := range refs[n] {
+			visit(byPath[ref.Target])
+		}
+
+		state[n] = 2
+		order = append(order, n)
+	}
+
+	for n := range sources {
+		visit(n)
+	}
+
+	var (
+		assets = make([]asset, len(sources))
+		paths  = make(map[string]string, len(sources))
+	)
+
+	for _, n := range order {
+		s := sources[n]
+		assets[n] = newAsset(rewrite(s.Data, refs[n], paths), s.Path, s.Date, o)
+		assets[n].File = s.File
+		paths[s.Path] = assets[n].Path
+	}
+
+	return assets
+}
+
+// rewrite replaces the last path segment of each reference with that of the
+// fingerprinted path of its target.
+func rewrite(data []byte, refs []reference, paths map[string]string) []byte {
+	if len(refs) == 0 {
+		return data
+	}
+
+	out := make([]byte, 0, len(data)+len(refs)*(fingerprintBytes*2+1))
+	last := 0
+
+	for _, ref := range refs {
+		fp := paths[ref.Target]
+		if fp == "" || fp == ref.Target {
+			continue
+		}
+
+		raw := string(data[ref.Start:ref.End])
+
+		end := strings.IndexAny(raw, "?#")
+		if end < 0 {
+			end = len(raw)
+		}
+
+		slash := strings.LastIndexByte(raw[:end], '/')
+
+		out = append(out, data[last:ref.Start]...)
+		out = append(out, raw[:slash+1]...)
+		out = append(out, url.PathEscape(path.Base(fp))...)
+		out = append(out, raw[end:]...)
+		last = ref.End
+	}
+
+	return append(out, data[last:]...)
+}
+
+// references returns the local references found in HTML and CSS files.
+func references(webPath string, data []byte) []reference {
+	switch strings.ToLower(path.Ext(webPath)) {
+	case ".html", ".htm":
+		return htmlReferences(webPath, data)
+	case ".css":
+		return cssReferences(webPath, data, 0)
+	}
+
+	return nil
+}
+
+// resolve returns the web path referenced by the given URL, relative to the
+// web path of the referencing file.
+func resolve(base, raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" || u.Path == "" {
+		return "", false
+	}
+
+	if strings.HasPrefix(u.Path, "/") {
+		return path.Clean(u.Path), true
+	}
+
+	return path.Join(path.Dir(base), u.Path), true
+}
+
+func htmlReferences(webPath string, data []byte) []reference {
+	var refs []reference
+
+	for i := 0; i < len(data); {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte("<!--")):
+			end := bytes.Index(data[i+4:], []byte("-->"))
+			if end < 0 {
+				return refs
+			}
+
+			i += end + 7
+		case data[i] == '<' && i+1 < len(data) && (data[i+1] == '!' || data[i+1] == '?'):
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return refs
+			}
+
+			i += end + 1
+		case data[i] == '<' && i+1 < len(data) && isLetter(data[i+1]):
+			tag, end, ok := htmlTag(webPath, data, i, &refs)
+			if !ok {
+				return refs
+			}
+
+			i = end
+
+			switch tag {
+			case "script", "style", "textarea", "title":
+				close := indexFold(data[i:], "</"+tag)
+				if close < 0 {
+					return refs
+				}
+
+				if tag == "style" {
+					refs = append(refs, cssReferences(webPath, data[i:i+close], i)...)
+				}
+
+				i += close
+			}
+		default:
+			i++
+		}
+	}
+
+	return refs
+}
+
+// htmlTag parses the tag starting at data[start], adding the values of any src
+// and href attributes to refs, and returns the lowercased tag name, the
+// position after the end of the tag, and whether the tag was terminated.
+func htmlTag(webPath string, data []byte, start int, refs *[]reference) (string, int, bool) {
+	i := start + 1
+
+	for i < len(data) && !isSpace(data[i]) && data[i] != '>' && data[i] != '/' {
+		i++
+	}
+
+	tag := strings.ToLower(string(data[start+1 : i]))
+
+	for i < len(data) {
+		switch c := data[i]; {
+		case c == '>':
+			if data[i-1] == '/' {
+				tag = ""
+			}
+
+			return tag, i + 1, true
+		case isSpace(c) || c == '/':
+			i++
+		default:
+			nameStart := i
+
+			for i < len(data) && !isSpace(data[i]) && data[i] != '=' && data[i] != '>' && data[i] != '/' {
+				i++
+			}
+
+			name := strings.ToLower(string(data[nameStart:i]))
+
+			for i < len(data) && isSpace(data[i]) {
+				i++
+			}
+
+			if i >= len(data) || data[i] != '=' {
+				continue
+			}
+
+			for i++; i < len(data) && isSpace(data[i]); i++ {
+			}
+
+			if i >= len(data) {
+				break
+			}
+
+			var valStart, valEnd int
+
+			if q := data[i]; q == '"' || q == '\'' {
+				end := bytes.IndexByte(data[i+1:], q)
+				if end < 0 {
+					return "", 0, false
+				}
+
+				valStart, valEnd = i+1, i+1+end
+				i = valEnd + 1
+			} else {
+				valStart = i
+
+				for i < len(data) && !isSpace(data[i]) && data[i] != '>' {
+					i++
+				}
+
+				valEnd = i
+			}
+
+			if name == "src" || name == "href" {
+				if target, ok := resolve(webPath, html.UnescapeString(string(data[valStart:valEnd]))); ok {
+					*refs = append(*refs, reference{Start: valStart, End: valEnd, Target: target})
+				}
+			}
+		}
+	}
+
+	return "", 0, false
+}
+
+// cssReferences returns the url() and @import references in the given CSS,
+// with offsets adjusted by the given amount.
+func cssReferences(webPath string, data []byte, offset int) []reference {
+	var refs []reference
+
+	add := func(start, end int) {
+		if raw := string(data[start:end]); !strings.ContainsRune(raw, '\\') {
+			if target, ok := resolve(webPath, raw); ok {
+				refs = append(refs, reference{Start: start + offset, End: end + offset, Target: target})
+			}
+		}
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return refs
+			}
+
+			i += end + 4
+		case c == '"' || c == '\'':
+			end, err := scanString(data, i)
+			if err != nil {
+				return refs
+			}
+
+			if bytes.HasSuffix(bytes.TrimRight(data[:i], " \t\r\n\f"), []byte("@import")) {
+				add(i+1, end-1)
+			}
+
+			i = end
+		case (c == 'u' || c == 'U') && (i == 0 || !isIdent(data[i-1]) && data[i-1] != '-') && i+4 <= len(data) && strings.EqualFold(string(data[i:i+4]), "url("):
+			i += 4
+
+			for i < len(data) && isSpace(data[i]) {
+				i++
+			}
+
+			if i < len(data) && (data[i] == '"' || data[i] == '\'') {
+				end, err := scanString(data, i)
+				if err != nil {
+					return refs
+				}
+
+				add(i+1, end-1)
+
+				i = end
+			} else {
+				end := bytes.IndexByte(data[i:], ')')
+				if end < 0 {
+					return refs
+				}
+
+				add(i, i+len(bytes.TrimRight(data[i:i+end], " \t\r\n\f")))
+
+				i += end + 1
+			}
+		default:
+			i++
+		}
+	}
+
+	return refs
+}
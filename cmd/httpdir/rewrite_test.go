@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	for n, test := range [...]struct {
+		Path, Data string
+		Targets    []string
+	}{
+		{ // 1
+			Path: "/index.html",
+			Data: `<!DOCTYPE html><link rel="stylesheet" href="css/style.css"><script src='/js/app.js?v=1'></script><img src=img/a.png alt=""><a href="https://example.com/x.js">x</a><a href="#top">top</a>`,
+			Targets: []string{
+				"/css/style.css",
+				"/js/app.js",
+				"/img/a.png",
+			},
+		},
+		{ // 2
+			Path: "/dir/page.html",
+			Data: `<!-- <img src="a.png"> --><script>var a = '<img src="b.png">';</script><style>body { background: url(../c.png) }</style><IMG SRC = "d%20e.png" />`,
+			Targets: []string{
+				"/c.png",
+				"/dir/d e.png",
+			},
+		},
+		{ // 3
+			Path: "/css/style.css",
+			Data: `@import "base.css"; @import url('/css/print.css') print; /* url(a.png) */ a { background: url( ../img/b.png#x ) } b { content: "url(c.png)"; background: URL(data:image/png;base64,AAAA) } c { background: my-url(d.png) }`,
+			Targets: []string{
+				"/css/base.css",
+				"/css/print.css",
+				"/img/b.png",
+			},
+		},
+		{ // 4
+			Path: "/js/app.js",
+			Data: `var a = "url(b.png)";`,
+		},
+		{ // 5
+			Path: "/index.html",
+			Data: `<img src="a.png"><img src="b.png`,
+			Targets: []string{
+				"/a.png",
+			},
+		},
+	} {
+		var targets []string
+
+		for _, ref := range references(test.Path, []byte(test.Data)) {
+			targets = append(targets, ref.Target)
+		}
+
+		if !reflect.DeepEqual(targets, test.Targets) {
+			t.Errorf("test %d: expecting targets %q, got %q", n+1, test.Targets, targets)
+		}
+	}
+}
+
+func TestRewriteAssets(t *testing.T) {
+	assets := rewriteAssets([]source{
+		{Path: "/index.html", Data: []byte(`<link rel="stylesheet" href="css/style.css?v=1"><img src="/img/a.png"><a href="about.html">About</a>`)},
+		{Path: "/about.html", Data: []byte(`<a href="index.html">Home</a>`)},
+		{Path: "/css/style.css", Data: []byte(`a { background: url("../img/a.png") } b { background: url(../img/missing.png) }`)},
+		{Path: "/img/a.png", Data: []byte("PNG")},
+	}, options{Fingerprint: true})
+
+	img := fingerprint("/img/a.png", []byte("PNG"))
+	css := fingerprint("/css/style.css", []byte(`a { background: url("../img/`+img[5:]+`") } b { background: url(../img/missing.png) }`))
+
+	for n, test := range [...]struct {
+		Path, Data string
+	}{
+		{"/index.html", `<link rel="stylesheet" href="css/` + css[5:] + `?v=1"><img src="` + img + `"><a href="about.html">About</a>`},
+		{"/about.html", `<a href="index.html">Home</a>`},
+		{css, `a { background: url("../img/` + img[5:] + `") } b { background: url(../img/missing.png) }`},
+		{img, "PNG"},
+	} {
+		if assets[n].Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Path, assets[n].Path)
+		} else if data := string(assets[n].Encs[0].Buffer); data != test.Data {
+			t.Errorf("test %d: expecting data %q, got %q", n+1, test.Data, data)
+		}
+	}
+
+	if !strings.HasPrefix(img, "/img/a.") || !strings.HasPrefix(css, "/css/style.") {
+		t.Errorf("unexpected fingerprints: %q, %q", img, css)
+	}
+}
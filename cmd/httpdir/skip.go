@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"strings"
+)
+
+var extraTypes = map[string]string{
+	".7z":    "application/x-7z-compressed",
+	".br":    "application/x-brotli",
+	".bz2":   "application/x-bzip2",
+	".gz":    "application/gzip",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".ogg":   "audio/ogg",
+	".rar":   "application/vnd.rar",
+	".webm":  "video/webm",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".xz":    "application/x-xz",
+	".zip":   "application/zip",
+	".zst":   "application/zstd",
+}
+
+var compressedTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/vnd.rar":          true,
+	"application/x-7z-compressed":  true,
+	"application/x-brotli":         true,
+	"application/x-bzip2":          true,
+	"application/x-gzip":           true,
+	"application/x-rar-compressed": true,
+	"application/x-xz":             true,
+	"application/zip":              true,
+	"application/zstd":             true,
+	"audio/aac":                    true,
+	"audio/mp4":                    true,
+	"audio/mpeg":                   true,
+	"audio/ogg":                    true,
+	"audio/webm":                   true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+	"image/avif":                   true,
+	"image/gif":                    true,
+	"image/jpeg":                   true,
+	"image/png":                    true,
+	"image/webp":                   true,
+	"video/mp4":                    true,
+	"video/mpeg":                   true,
+	"video/webm":                   true,
+}
+
+func mimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := extraTypes[ext]; ok {
+		return t
+	}
+
+	t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+
+	return t
+}
+
+type droppedVariant struct {
+	Path, Ext, Reason string
+	Size, Original    int
+}
+
+var dropped []droppedVariant
+
+func (a *asset) dropVariants() {
+	if a.Opts.SkipCompressedTypes && len(a.Encs) > 1 {
+		if t := mimeType(a.Path); compressedTypes[t] {
+			a.drop(func(encoding) bool { return true }, "already compressed type "+t)
+
+			return
+		}
+	}
+
+	a.drop(func(enc encoding) bool {
+		saved := a.Size - len(enc.Buffer)
+
+		if a.Opts.MinSavingsBytes > 0 && saved < a.Opts.MinSavingsBytes {
+			return true
+		}
+
+		return a.Opts.MinSavingsRatio > 0 && a.Size > 0 && float64(saved)/float64(a.Size) < a.Opts.MinSavingsRatio
+	}, "insufficient savings")
+}
+
+func (a *asset) drop(fn func(encoding) bool, reason string) {
+	encs := a.Encs[:0]
+
+	for _, enc := range a.Encs {
+		if enc.Ext != "" && fn(enc) {
+			dropped = append(dropped, droppedVariant{
+				Path:     a.Path,
+				Ext:      enc.Ext,
+				Reason:   reason,
+				Size:     len(enc.Buffer),
+				Original: a.Size,
+			})
+		} else {
+			encs = append(encs, enc)
+		}
+	}
+
+	a.Encs = encs
+}
+
+func printDropped() {
+	if len(dropped) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "dropped %d compressed variant(s):\n", len(dropped))
+
+	for _, d := range dropped {
+		fmt.Fprintf(os.Stderr, "\t%s%s: %d bytes (original %d bytes): %s\n", d.Path, d.Ext, d.Size, d.Original, d.Reason)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDropVariants(t *testing.T) {
+	defer func() { dropped = nil }()
+
+	for n, test := range [...]struct {
+		MinBytes int
+		MinRatio float64
+		Exts     []string
+	}{
+		{MinBytes: 1, Exts: []string{".br", ""}},
+		{MinBytes: 30, Exts: []string{".br", ""}},
+		{MinBytes: 31, Exts: []string{""}},
+		{MinRatio: 0.3, Exts: []string{".br", ""}},
+		{MinRatio: 0.4, Exts: []string{""}},
+		{MinBytes: 1, MinRatio: 0.4, Exts: []string{""}},
+		{Exts: []string{".br", "", ".gz"}},
+	} {
+		a := asset{
+			Path: "/file.txt",
+			Size: 100,
+			Encs: encodings{{Buffer: make([]byte, 70), Ext: ".br"}, {Buffer: make([]byte, 100)}, {Buffer: make([]byte, 120), Ext: ".gz"}},
+			Opts: options{MinSavingsBytes: test.MinBytes, MinSavingsRatio: test.MinRatio},
+		}
+
+		a.dropVariants()
+
+		var exts []string
+
+		for _, enc := range a.Encs {
+			exts = append(exts, enc.Ext)
+		}
+
+		if !reflect.DeepEqual(exts, test.Exts) {
+			t.Errorf("test %d: expecting variants %q, got %q", n+1, test.Exts, exts)
+		}
+	}
+}
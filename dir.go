@@ -2,11 +2,10 @@
 package httpdir // import "vimagination.zapto.org/httpdir"
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 	"net/http"
-	"path"
-	"strings"
 	"time"
 )
 
@@ -35,60 +34,154 @@ func Remove(name string) error {
 }
 
 // Dir is the start of a simple in-memory filesystem tree.
+//
+// Names passed to the methods of Dir are slash-separated paths, with an
+// optional leading slash. Empty and '.' components are ignored, and names
+// containing '..' components or NUL bytes are rejected.
+//
+// Errors returned from the methods of Dir are of type *fs.PathError, with the
+// Path set to the name, with a leading slash and without empty or '.'
+// components, up to and including the component that caused the error.
 type Dir struct {
-	d dir
+	d                *dir
+	propagateModTime bool
+}
+
+// Option is a function that can be passed to New to modify the behaviour of
+// the created Dir.
+type Option func(*Dir)
+
+// PropagateModTime is an Option that causes the modification time of every
+// ancestor directory to be updated to the current time whenever a node is
+// created or removed beneath it.
+//
+// This allows caching mechanisms to detect changes to directory listings.
+func PropagateModTime(d *Dir) {
+	d.propagateModTime = true
+}
+
+// CaseInsensitive is an Option that causes all names to be resolved using
+// Unicode case folding.
+//
+// The original name of each node is retained for directory listings, and an
+// error will be returned when attempting to create a node whose name differs
+// only in case from an existing node.
+func CaseInsensitive(d *Dir) {
+	d.d.folded = make(map[string]string)
 }
 
 // New creates a new, initialised, Dir.
-func New(t time.Time) Dir {
-	return Dir{
-		dir{
+func New(t time.Time, opts ...Option) Dir {
+	d := Dir{
+		d: &dir{
 			modTime:  t,
 			contents: make(map[string]Node),
 		},
 	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return d
 }
 
 // Open returns the file, or directory, specified by the given name.
 //
 // This method is the implementation of http.FileSystem and isn't intended to
 // be used by clients of this package.
+//
+// The returned http.File also implements fs.ReadDirFile.
+//
+// Names that would be rejected by the other methods, or that descend through
+// a file, result in an error matching fs.ErrNotExist rather than fs.ErrInvalid
+// so that http.FileServer responds with a 404 Not Found.
 func (d Dir) Open(name string) (http.File, error) {
-	n, err := d.get(name)
+	n, err := d.get("open", name)
+	if err != nil {
+		return nil, notExist(err)
+	}
+
+	f, err := n.Open()
+	if err != nil {
+		var pe *fs.PathError
+
+		if !errors.As(err, &pe) {
+			err = &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Stat returns the FileInfo of the file, or directory, specified by the given
+// name.
+//
+// Unlike Open, the node is not opened, so Stat will succeed for directories
+// that do not allow a directory listing.
+func (d Dir) Stat(name string) (fs.FileInfo, error) {
+	n, err := d.get("stat", name)
 	if err != nil {
 		return nil, err
 	}
 
-	return n.Open()
+	return n, nil
 }
 
-func (d Dir) get(name string) (namedNode, error) {
-	name = path.Clean(name)
-	if len(name) > 0 && name[0] == '/' {
-		name = name[1:]
+func (d Dir) get(op, name string) (namedNode, error) {
+	parts, err := splitPath(op, name, false)
+	if err != nil {
+		return namedNode{}, err
 	}
 
 	n := namedNode{"", d.d}
 
-	if len(name) > 0 {
-		for _, part := range strings.Split(name, "/") {
-			nd, ok := n.Node.(dir)
-			if !ok {
-				return namedNode{}, fs.ErrInvalid
-			}
+	for p, part := range parts {
+		nd, ok := n.Node.(*dir)
+		if !ok {
+			return namedNode{}, pathError(op, parts[:p], ErrNotDirectory)
+		}
 
-			dn, ok := nd.contents[part]
-			if !ok {
-				return namedNode{}, fs.ErrNotExist
-			}
+		name, dn, ok := nd.lookup(part)
+		if !ok {
+			return namedNode{}, pathError(op, parts[:p+1], fs.ErrNotExist)
+		}
 
-			n = namedNode{part, dn}
+		switch nd.policy(name) {
+		case PolicyDeny:
+			return namedNode{}, pathError(op, parts[:p+1], fs.ErrPermission)
+		case PolicyNotFound:
+			return namedNode{}, pathError(op, parts[:p+1], fs.ErrNotExist)
 		}
+
+		n = namedNode{name, dn}
 	}
 
 	return n, nil
 }
 
+func (d Dir) getPath(op string, parts []string) ([]*dir, error) {
+	dirs := []*dir{d.d}
+
+	for p, part := range parts {
+		_, n, ok := dirs[len(dirs)-1].lookup(part)
+		if !ok {
+			return nil, pathError(op, parts[:p+1], fs.ErrNotExist)
+		}
+
+		nd, ok := n.(*dir)
+		if !ok {
+			return nil, pathError(op, parts[:p+1], ErrNotDirectory)
+		}
+
+		dirs = append(dirs, nd)
+	}
+
+	return dirs, nil
+}
+
 // Mkdir creates the named directory, and any parent directories required.
 //
 // modTime is the modification time of the directory, used in caching
@@ -101,41 +194,65 @@ func (d Dir) get(name string) (namedNode, error) {
 // All directories created will be given the specified modification time and
 // index bool.
 //
-// Directories already existing will not be modified.
+// Directories already existing will not be modified, unless the Dir was
+// created with the PropagateModTime Option, in which case the modification
+// time of the pre-existing ancestors of any newly created directory will be
+// updated.
 func (d Dir) Mkdir(name string, modTime time.Time, index bool) error {
-	_, err := d.makePath(path.Clean(name), modTime, index)
+	parts, err := splitPath("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+
+	dirs, existing, err := d.makePath("mkdir", parts, modTime, index)
+	if err == nil && existing < len(dirs) {
+		d.touch(dirs[:existing])
+	}
 
 	return err
 }
 
-func (d Dir) makePath(name string, modTime time.Time, index bool) (dir, error) {
-	name = strings.TrimPrefix(name, "/")
+func (d Dir) makePath(op string, parts []string, modTime time.Time, index bool) ([]*dir, int, error) {
 	td := d.d
+	dirs := []*dir{td}
+	existing := 1
 
-	for _, part := range strings.Split(name, "/") {
-		if part == "" {
-			continue
-		}
-
-		if n, ok := td.contents[part]; ok {
+	for p, part := range parts {
+		if _, n, ok := td.lookup(part); ok {
 			switch f := n.(type) {
-			case dir:
+			case *dir:
 				td = f
+
+				if existing == len(dirs) {
+					existing++
+				}
 			default:
-				return dir{}, fs.ErrInvalid
+				return nil, 0, pathError(op, parts[:p+1], ErrNotDirectory)
 			}
 		} else {
-			nd := dir{
-				index:    index,
-				contents: make(map[string]Node),
-				modTime:  modTime,
-			}
-			td.contents[part] = nd
+			nd := td.newDir(modTime, index)
+			td.set(part, nd)
 			td = nd
 		}
+
+		dirs = append(dirs, td)
+	}
+
+	return dirs, existing, nil
+}
+
+func (d Dir) touch(dirs []*dir) {
+	if !d.propagateModTime {
+		return
 	}
 
-	return td, nil
+	now := time.Now()
+
+	for _, dn := range dirs {
+		if now.After(dn.modTime) {
+			dn.modTime = now
+		}
+	}
 }
 
 // Create places a Node into the directory tree.
@@ -145,19 +262,31 @@ func (d Dir) makePath(name string, modTime time.Time, index bool) (dir, error) {
 //
 // If you want to specify alternate modTime/index values for the directories,
 // then you should create them first with Mkdir.
+//
+// If the Dir was created with the PropagateModTime Option, the modification
+// time of every pre-existing ancestor directory will be updated.
 func (d Dir) Create(name string, n Node) error {
-	dname, fname := path.Split(name)
+	parts, err := splitPath("create", name, true)
+	if err != nil {
+		return err
+	}
 
-	dn, err := d.makePath(dname, n.ModTime(), false)
+	fname := parts[len(parts)-1]
+
+	dirs, existing, err := d.makePath("create", parts[:len(parts)-1], n.ModTime(), false)
 	if err != nil {
 		return err
 	}
 
-	if _, ok := dn.contents[fname]; ok {
-		return fs.ErrExist
+	dn := dirs[len(dirs)-1]
+
+	if _, _, ok := dn.lookup(fname); ok {
+		return pathError("create", parts, fs.ErrExist)
 	}
 
-	dn.contents[fname] = n
+	dn.set(fname, n)
+
+	d.touch(dirs[:existing])
 
 	return nil
 }
@@ -166,21 +295,29 @@ func (d Dir) Create(name string, n Node) error {
 //
 // It will remove files and any directories, whether they are empty or not.
 //
+// If the Dir was created with the PropagateModTime Option, the modification
+// time of every ancestor directory will be updated.
+//
 // Caution: httpdir does no internal locking, so you should provide your own if
 // you intend to call this method.
 func (d Dir) Remove(name string) error {
-	dname, fname := path.Split(name)
+	parts, err := splitPath("remove", name, true)
+	if err != nil {
+		return err
+	}
 
-	nn, err := d.get(dname)
+	dirs, err := d.getPath("remove", parts[:len(parts)-1])
 	if err != nil {
 		return err
 	}
 
-	if nd, ok := nn.Node.(dir); ok {
-		return nd.Remove(fname)
+	if err := dirs[len(dirs)-1].Remove(parts[len(parts)-1]); err != nil {
+		return pathError("remove", parts, err)
 	}
 
-	return fs.ErrInvalid
+	d.touch(dirs)
+
+	return nil
 }
 
 // Node represents a data file in the tree.
@@ -208,6 +345,14 @@ func (n namedNode) Sys() interface{} {
 	return n.Node
 }
 
+func (n namedNode) Type() fs.FileMode {
+	return n.Mode().Type()
+}
+
+func (n namedNode) Info() (fs.FileInfo, error) {
+	return n, nil
+}
+
 func (n namedNode) Open() (http.File, error) {
 	f, err := n.Node.Open()
 	if err != nil {
@@ -233,3 +378,26 @@ type wrapped struct {
 func (w wrapped) Stat() (fs.FileInfo, error) {
 	return w.FileInfo, nil
 }
+
+// ReadDir implements fs.ReadDirFile, falling back to Readdir when the
+// underlying File does not have its own ReadDir method.
+func (w wrapped) ReadDir(n int) ([]fs.DirEntry, error) {
+	if rd, ok := w.File.(interface {
+		ReadDir(int) ([]fs.DirEntry, error)
+	}); ok {
+		return rd.ReadDir(n)
+	}
+
+	fis, err := w.Readdir(n)
+	if err == io.EOF && n <= 0 {
+		err = nil
+	}
+
+	des := make([]fs.DirEntry, len(fis))
+
+	for n, fi := range fis {
+		des[n] = fs.FileInfoToDirEntry(fi)
+	}
+
+	return des, err
+}
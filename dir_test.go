@@ -1,7 +1,10 @@
 package httpdir
 
 import (
+	"errors"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -24,7 +27,7 @@ func TestDir(t *testing.T) {
 		return
 	}
 	_, err = d.Open("/dir2")
-	if err != fs.ErrPermission {
+	if !errors.Is(err, fs.ErrPermission) {
 		t.Errorf("expecting permission error, got %q", err)
 	}
 	err = d.Create("/dir3/test/hello", FileString("Hello, World!", time.Now()))
@@ -61,7 +64,7 @@ func TestDir(t *testing.T) {
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
 	}
-	if len(d.d.contents["dir3"].(dir).contents["test"].(dir).contents) != 0 {
+	if len(d.d.contents["dir3"].(*dir).contents["test"].(*dir).contents) != 0 {
 		t.Errorf("did not delete '/dir3/test/hello'")
 		return
 	}
@@ -73,3 +76,418 @@ func TestDir(t *testing.T) {
 		t.Errorf("did not delete '/dir3'")
 	}
 }
+
+func TestPropagateModTime(t *testing.T) {
+	old := time.Unix(1000, 0)
+	d := New(old, PropagateModTime)
+
+	if err := d.Mkdir("/a/b/c", old, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Mkdir("/x", old, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, dn := range []Node{d.d, d.d.contents["x"]} {
+		dn.(*dir).modTime = old
+	}
+
+	a := d.d.contents["a"].(*dir)
+	b := a.contents["b"].(*dir)
+	c := b.contents["c"].(*dir)
+
+	if !a.modTime.Equal(old) || !b.modTime.Equal(old) || !c.modTime.Equal(old) {
+		t.Fatalf("expecting new directories to have modTime %v", old)
+	}
+
+	if err := d.Create("/a/b/file", FileString("", old)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, dn := range map[string]*dir{"/": d.d, "/a": a, "/a/b": b} {
+		if !dn.modTime.After(old) {
+			t.Errorf("expecting modTime of %q to be updated", n)
+		}
+	}
+
+	if !c.modTime.Equal(old) {
+		t.Errorf("expecting modTime of \"/a/b/c\" to remain unchanged")
+	}
+
+	if x := d.d.contents["x"].(*dir); !x.modTime.Equal(old) {
+		t.Errorf("expecting modTime of \"/x\" to remain unchanged")
+	}
+
+	for _, dn := range []*dir{d.d, a, b} {
+		dn.modTime = old
+	}
+
+	if err := d.Remove("/a/b/c"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, dn := range map[string]*dir{"/": d.d, "/a": a, "/a/b": b} {
+		if !dn.modTime.After(old) {
+			t.Errorf("expecting modTime of %q to be updated", n)
+		}
+	}
+
+	if err := d.Create("/a/new/file", FileString("", old)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if nd := a.contents["new"].(*dir); !nd.modTime.Equal(old) {
+		t.Errorf("expecting modTime of new directory \"/a/new\" to be %v, got %v", old, nd.modTime)
+	}
+
+	nd := New(old)
+
+	if err := nd.Create("/a/file", FileString("", old)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !nd.d.modTime.Equal(old) {
+		t.Errorf("expecting modTime to be unchanged without PropagateModTime")
+	}
+}
+
+func TestStat(t *testing.T) {
+	mt := time.Unix(1000, 0)
+	d := New(mt)
+
+	if err := d.Mkdir("/dir", mt, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Create("/dir/file", FileString("Hello, World!", mt)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Name  string
+		Base  string
+		Size  int64
+		IsDir bool
+		Err   error
+	}{
+		{
+			Name:  "/dir",
+			Base:  "dir",
+			IsDir: true,
+		},
+		{
+			Name: "/dir/file",
+			Base: "file",
+			Size: 13,
+		},
+		{
+			Name: "/dir/missing",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Name: "/dir/file/child",
+			Err:  fs.ErrInvalid,
+		},
+	} {
+		fi, err := d.Stat(test.Name)
+		if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err == nil {
+			if fi.Name() != test.Base {
+				t.Errorf("test %d: expecting name %q, got %q", n+1, test.Base, fi.Name())
+			} else if fi.Size() != test.Size {
+				t.Errorf("test %d: expecting size %d, got %d", n+1, test.Size, fi.Size())
+			} else if fi.IsDir() != test.IsDir {
+				t.Errorf("test %d: expecting IsDir %v, got %v", n+1, test.IsDir, fi.IsDir())
+			} else if !fi.ModTime().Equal(mt) {
+				t.Errorf("test %d: expecting modTime %v, got %v", n+1, mt, fi.ModTime())
+			}
+		}
+	}
+}
+
+func TestPathErrors(t *testing.T) {
+	d := New(time.Now())
+
+	if err := d.Create("/a/b", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Mkdir("/c", time.Now(), false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Fn       func() error
+		Op, Path string
+		Err      error
+	}{
+		{
+			Fn:   func() error { _, err := d.Open("/a/b/c/d"); return err },
+			Op:   "open",
+			Path: "/a/b",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { _, err := d.Stat("/a/b/c/d"); return err },
+			Op:   "stat",
+			Path: "/a/b",
+			Err:  ErrNotDirectory,
+		},
+		{
+			Fn:   func() error { _, err := d.Open("a//./../b"); return err },
+			Op:   "open",
+			Path: "/a/..",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { _, err := d.Open("/a/x/y"); return err },
+			Op:   "open",
+			Path: "/a/x",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { _, err := d.Open("/c"); return err },
+			Op:   "open",
+			Path: "/c",
+			Err:  fs.ErrPermission,
+		},
+		{
+			Fn:   func() error { _, err := d.Stat("/x"); return err },
+			Op:   "stat",
+			Path: "/x",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { return d.Create("/a/b", FileString("", time.Now())) },
+			Op:   "create",
+			Path: "/a/b",
+			Err:  fs.ErrExist,
+		},
+		{
+			Fn:   func() error { return d.Create("/a/b/c/d", FileString("", time.Now())) },
+			Op:   "create",
+			Path: "/a/b",
+			Err:  fs.ErrInvalid,
+		},
+		{
+			Fn:   func() error { return d.Mkdir("/a/b/c", time.Now(), false) },
+			Op:   "mkdir",
+			Path: "/a/b",
+			Err:  fs.ErrInvalid,
+		},
+		{
+			Fn:   func() error { return d.Remove("/a/x") },
+			Op:   "remove",
+			Path: "/a/x",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { return d.Remove("/x/y/z") },
+			Op:   "remove",
+			Path: "/x",
+			Err:  fs.ErrNotExist,
+		},
+		{
+			Fn:   func() error { return d.Remove("/a/b/c") },
+			Op:   "remove",
+			Path: "/a/b",
+			Err:  fs.ErrInvalid,
+		},
+		{
+			Fn:   func() error { return d.Create("a//./../b", FileString("", time.Now())) },
+			Op:   "create",
+			Path: "/a/..",
+			Err:  ErrParentDir,
+		},
+		{
+			Fn:   func() error { return d.Create("./a/b\x00c/d", FileString("", time.Now())) },
+			Op:   "create",
+			Path: "/a/b\x00c",
+			Err:  ErrNULByte,
+		},
+		{
+			Fn:   func() error { return d.Create("a//b/", FileString("", time.Now())) },
+			Op:   "create",
+			Path: "/a/b/",
+			Err:  ErrEmptyName,
+		},
+		{
+			Fn:   func() error { return d.Remove("") },
+			Op:   "remove",
+			Path: "/",
+			Err:  ErrEmptyName,
+		},
+	} {
+		var pe *fs.PathError
+
+		if err := test.Fn(); !errors.As(err, &pe) {
+			t.Errorf("test %d: expecting *fs.PathError, got %T", n+1, err)
+		} else if pe.Op != test.Op {
+			t.Errorf("test %d: expecting op %q, got %q", n+1, test.Op, pe.Op)
+		} else if pe.Path != test.Path {
+			t.Errorf("test %d: expecting path %q, got %q", n+1, test.Path, pe.Path)
+		} else if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, pe.Err)
+		}
+	}
+}
+
+func TestFileServerStatus(t *testing.T) {
+	d := New(time.Now())
+
+	if err := d.Create("/a/b", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Mkdir("/c", time.Now(), false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Path   string
+		Status int
+	}{
+		{Path: "/a/b", Status: http.StatusOK},
+		{Path: "/a/x", Status: http.StatusNotFound},
+		{Path: "/a/b/c", Status: http.StatusNotFound},
+		{Path: "/a/b%00c", Status: http.StatusNotFound},
+		{Path: "/c/", Status: http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+
+		http.FileServer(d).ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.Path, nil))
+
+		if w.Code != test.Status {
+			t.Errorf("test %d: expecting status %d, got %d", n+1, test.Status, w.Code)
+		}
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	d := New(time.Now(), CaseInsensitive)
+
+	if err := d.Mkdir("/Docs", time.Now(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Create("/docs/ReadMe.TXT", FileString("Hello", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Create("/DOCS/Straße", FileString("Street", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(d.d.contents) != 1 {
+		t.Fatalf("expecting 1 root entry, got %d", len(d.d.contents))
+	}
+
+	for _, name := range [...]string{"/docs/readme.txt", "/DOCS/README.TXT", "/dOcS/ReAdMe.TxT", "/docs/STRASSE", "/docs/STRAßE"} {
+		fi, err := d.Stat(name)
+		if name == "/docs/STRASSE" {
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s: expecting not exist error, got %v", name, err)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		} else if fi.Name() != "ReadMe.TXT" && fi.Name() != "Straße" {
+			t.Errorf("%s: expecting original name, got %q", name, fi.Name())
+		}
+	}
+
+	if err := d.Create("/docs/README.txt", FileString("", time.Now())); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expecting exist error, got %v", err)
+	}
+
+	f, err := d.Open("/DOCS")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fis, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(fis) != 2 || fis[0].Name() != "ReadMe.TXT" || fis[1].Name() != "Straße" {
+		t.Errorf("expecting original names in listing, got %v", fis)
+	}
+
+	if err := d.Remove("/docs/readme.txt"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := d.Stat("/docs/ReadMe.TXT"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expecting not exist error, got %v", err)
+	}
+
+	if err := d.Create("/docs/README.txt", FileString("", time.Now())); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	cs := New(time.Now())
+
+	if err := cs.Create("/a", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := cs.Stat("/A"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expecting not exist error for case-sensitive Dir, got %v", err)
+	}
+}
+
+func TestInvalidNames(t *testing.T) {
+	d := New(time.Now())
+
+	for n, test := range [...]struct {
+		Fn  func() error
+		Err error
+	}{
+		{
+			Fn:  func() error { return d.Create("/a/../b", FileString("", time.Now())) },
+			Err: ErrParentDir,
+		},
+		{
+			Fn:  func() error { return d.Create("/a/", FileString("", time.Now())) },
+			Err: ErrEmptyName,
+		},
+		{
+			Fn:  func() error { return d.Create("/a\x00", FileString("", time.Now())) },
+			Err: ErrNULByte,
+		},
+		{
+			Fn:  func() error { return d.Mkdir("/a/../b", time.Now(), false) },
+			Err: ErrParentDir,
+		},
+		{
+			Fn:  func() error { return d.Remove("/") },
+			Err: ErrEmptyName,
+		},
+		{
+			Fn:  func() error { _, err := d.Stat("/../a"); return err },
+			Err: ErrParentDir,
+		},
+		{
+			Fn:  func() error { _, err := d.Open("/../a"); return err },
+			Err: fs.ErrNotExist,
+		},
+	} {
+		if err := test.Fn(); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+
+	if len(d.d.contents) != 0 {
+		t.Errorf("expecting no nodes to be created, got %d", len(d.d.contents))
+	}
+
+	if err := d.Create("a//b", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := d.Stat("/a/b"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
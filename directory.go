@@ -4,61 +4,162 @@ import (
 	"io"
 	"io/fs"
 	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 type dir struct {
 	index    bool
 	contents map[string]Node
+	folded   map[string]string
 	modTime  time.Time
+	mu       sync.Mutex
+	listing  listing
+	parent   *dir
+	rules    []Rule
+	access   *Access
 }
 
-func (d dir) Size() int64 {
+func (d *dir) Size() int64 {
 	return 0
 }
 
-func (dir) Mode() fs.FileMode {
+func (*dir) Mode() fs.FileMode {
 	return ModeDir
 }
 
-func (d dir) ModTime() time.Time {
+func (d *dir) ModTime() time.Time {
 	return d.modTime
 }
 
-func (d dir) Open() (File, error) {
+func (d *dir) Open() (File, error) {
 	if !d.index {
-		if f, ok := d.contents["index.html"]; ok {
+		if name, f, ok := d.lookup("index.html"); ok && d.policy(name) <= PolicyHide {
 			return f.Open()
 		}
 
 		return nil, fs.ErrPermission
 	}
 
-	contents := make([]fs.FileInfo, 0, len(d.contents))
+	return &directory{contents: d.getListing()}, nil
+}
+
+// getListing returns the sorted listing of the allowed contents of the
+// directory, which is cached until the contents change. Open may be called
+// from many goroutines at once, so access to the cache is guarded.
+func (d *dir) getListing() listing {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.listing == nil {
+		d.listing = make(listing, 0, len(d.contents))
+
+		for name, node := range d.contents {
+			if d.policy(name) == PolicyAllow {
+				d.listing = append(d.listing, namedNode{name, node})
+			}
+		}
+
+		sort.Sort(d.listing)
+	}
+
+	return d.listing
+}
+
+func (d *dir) resetListing() {
+	d.mu.Lock()
+	d.listing = nil
+	d.mu.Unlock()
+}
+
+func (d *dir) newDir(modTime time.Time, index bool) *dir {
+	nd := &dir{
+		index:    index,
+		contents: make(map[string]Node),
+		modTime:  modTime,
+		parent:   d,
+	}
 
-	for name, node := range d.contents {
-		contents = append(contents, namedNode{name, node})
+	if d.folded != nil {
+		nd.folded = make(map[string]string)
 	}
 
-	dir := &directory{contents: contents}
+	return nd
+}
+
+func (d *dir) lookup(name string) (string, Node, bool) {
+	if d.folded != nil {
+		fname, ok := d.folded[foldName(name)]
+		if !ok {
+			return "", nil, false
+		}
 
-	sort.Sort(dir)
+		name = fname
+	}
 
-	return dir, nil
+	n, ok := d.contents[name]
+
+	return name, n, ok
 }
 
-func (d dir) Remove(name string) error {
-	if _, ok := d.contents[name]; !ok {
+func (d *dir) set(name string, n Node) {
+	d.contents[name] = n
+	d.resetListing()
+
+	if d.folded != nil {
+		d.folded[foldName(name)] = name
+	}
+}
+
+func (d *dir) Remove(name string) error {
+	name, _, ok := d.lookup(name)
+	if !ok {
 		return fs.ErrNotExist
 	}
 
 	delete(d.contents, name)
 
+	if d.folded != nil {
+		delete(d.folded, foldName(name))
+	}
+
+	d.resetListing()
+
 	return nil
 }
 
+func foldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+
+		return min
+	}, name)
+}
+
+type listing []namedNode
+
+func (l listing) Len() int {
+	return len(l)
+}
+
+func (l listing) Less(i, j int) bool {
+	return l[i].name < l[j].name
+}
+
+func (l listing) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
 type directory struct {
-	contents []fs.FileInfo
+	contents listing
 	pos      int
 }
 
@@ -80,42 +181,57 @@ func (d *directory) Seek(offset int64, whence int) (int64, error) {
 		pos += offset
 	case io.SeekEnd:
 		pos = int64(len(d.contents)) + offset
+	default:
+		return 0, fs.ErrInvalid
 	}
 
-	if pos != 0 {
+	if pos < 0 || pos > int64(len(d.contents)) {
 		return 0, fs.ErrInvalid
 	}
 
-	d.pos = 0
+	d.pos = int(pos)
 
-	return 0, nil
+	return pos, nil
 }
 
-func (d *directory) Readdir(n int) ([]fs.FileInfo, error) {
+func (d *directory) next(n int) listing {
 	if n < 0 || d.pos+n > len(d.contents) {
 		n = len(d.contents) - d.pos
 	}
 
 	last := d.pos + n
 	toRet := d.contents[d.pos:last]
+	d.pos = last
+
+	return toRet
+}
 
+func (d *directory) Readdir(n int) ([]fs.FileInfo, error) {
+	toRet := d.next(n)
 	if len(toRet) == 0 {
 		return nil, io.EOF
 	}
 
-	d.pos = last
+	fis := make([]fs.FileInfo, len(toRet))
 
-	return toRet, nil
-}
+	for n, nn := range toRet {
+		fis[n] = nn
+	}
 
-func (d *directory) Len() int {
-	return len(d.contents)
+	return fis, nil
 }
 
-func (d *directory) Less(i, j int) bool {
-	return d.contents[i].Name() < d.contents[j].Name()
-}
+func (d *directory) ReadDir(n int) ([]fs.DirEntry, error) {
+	toRet := d.next(n)
+	if len(toRet) == 0 && n > 0 {
+		return nil, io.EOF
+	}
+
+	des := make([]fs.DirEntry, len(toRet))
+
+	for n, nn := range toRet {
+		des[n] = nn
+	}
 
-func (d *directory) Swap(i, j int) {
-	d.contents[i], d.contents[j] = d.contents[j], d.contents[i]
+	return des, nil
 }
@@ -3,6 +3,7 @@ package httpdir
 import (
 	"io"
 	"io/fs"
+	"sync"
 	"testing"
 	"time"
 )
@@ -88,3 +89,188 @@ func TestDirectory(t *testing.T) {
 		return
 	}
 }
+
+func TestDirectorySeek(t *testing.T) {
+	mt := time.Now()
+	d := dir{
+		index: true,
+		contents: map[string]Node{
+			"a": FileString("", mt),
+			"b": FileString("", mt),
+			"c": FileString("", mt),
+			"d": FileString("", mt),
+		},
+		modTime: mt,
+	}
+
+	f, err := d.Open()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for n, test := range [...]struct {
+		Offset int64
+		Whence int
+		Pos    int64
+		Err    error
+		Next   string
+	}{
+		{Offset: 2, Whence: io.SeekStart, Pos: 2, Next: "c"},
+		{Offset: -2, Whence: io.SeekCurrent, Pos: 1, Next: "b"},
+		{Offset: -1, Whence: io.SeekEnd, Pos: 3, Next: "d"},
+		{Offset: 0, Whence: io.SeekEnd, Pos: 4},
+		{Offset: 5, Whence: io.SeekStart, Err: fs.ErrInvalid},
+		{Offset: -1, Whence: io.SeekStart, Err: fs.ErrInvalid},
+		{Offset: 0, Whence: 3, Err: fs.ErrInvalid},
+	} {
+		pos, err := f.Seek(test.Offset, test.Whence)
+		if err != test.Err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err != nil {
+			continue
+		} else if pos != test.Pos {
+			t.Errorf("test %d: expecting position %d, got %d", n+1, test.Pos, pos)
+		} else if c, err := f.Readdir(1); test.Next == "" && err != io.EOF {
+			t.Errorf("test %d: expecting EOF, got %v", n+1, err)
+		} else if test.Next != "" && (err != nil || c[0].Name() != test.Next) {
+			t.Errorf("test %d: expecting file %q, got %v (%v)", n+1, test.Next, c, err)
+		}
+	}
+}
+
+func TestDirectoryReadDir(t *testing.T) {
+	mt := time.Now()
+	d := dir{
+		index: true,
+		contents: map[string]Node{
+			"file": FileString("", mt),
+			"dir":  &dir{},
+		},
+		modTime: mt,
+	}
+
+	f, err := d.Open()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rdf, ok := f.(interface {
+		ReadDir(int) ([]fs.DirEntry, error)
+	})
+	if !ok {
+		t.Fatal("expecting directory to implement ReadDir")
+	}
+
+	des, err := rdf.ReadDir(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(des) != 1 || des[0].Name() != "dir" || !des[0].IsDir() || des[0].Type() != fs.ModeDir {
+		t.Errorf("expecting directory entry \"dir\", got %v", des)
+	}
+
+	des, err = rdf.ReadDir(-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(des) != 1 || des[0].Name() != "file" || des[0].IsDir() {
+		t.Errorf("expecting file entry \"file\", got %v", des)
+	}
+
+	if des, err = rdf.ReadDir(-1); err != nil || len(des) != 0 {
+		t.Errorf("expecting no entries and no error, got %v, %v", des, err)
+	}
+
+	if _, err = rdf.ReadDir(1); err != io.EOF {
+		t.Errorf("expecting EOF, got %v", err)
+	}
+}
+
+func TestDirectoryListingCache(t *testing.T) {
+	d := New(time.Now())
+
+	if err := d.Mkdir("/dir", time.Now(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Create("/dir/a", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	names := func() []string {
+		f, err := d.Open("/dir")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		des, err := f.(fs.ReadDirFile).ReadDir(-1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var names []string
+
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+
+		return names
+	}
+
+	if n := names(); len(n) != 1 || n[0] != "a" {
+		t.Errorf("expecting [a], got %v", n)
+	}
+
+	if err := d.Create("/dir/b", FileString("", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n := names(); len(n) != 2 || n[0] != "a" || n[1] != "b" {
+		t.Errorf("expecting [a b], got %v", n)
+	}
+
+	if err := d.Remove("/dir/a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n := names(); len(n) != 1 || n[0] != "b" {
+		t.Errorf("expecting [b], got %v", n)
+	}
+}
+
+func TestDirectoryConcurrentOpen(t *testing.T) {
+	d := New(time.Now())
+
+	if err := d.Mkdir("/dir", time.Now(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, name := range [...]string{"/dir/a", "/dir/b", "/dir/c"} {
+		if err := d.Create(name, FileString("", time.Now())); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			f, err := d.Open("/dir")
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+
+				return
+			}
+
+			if fis, err := f.Readdir(-1); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if len(fis) != 3 {
+				t.Errorf("expecting 3 entries, got %d", len(fis))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -2,6 +2,7 @@ package httpdir
 
 import (
 	"bytes"
+	"io"
 	"io/fs"
 	"os"
 	"strings"
@@ -92,6 +93,113 @@ func (fileStringOpen) Close() error {
 	return nil
 }
 
+type fileStrings struct {
+	data    []string
+	size    int64
+	modTime time.Time
+}
+
+// FileStrings provides an implementation of Node that takes a slice of strings
+// as its data source, read in order as if they were concatenated.
+//
+// This allows large data to be split across several string literals without
+// being copied.
+func FileStrings(data []string, modTime time.Time) Node {
+	var size int64
+
+	for _, s := range data {
+		size += int64(len(s))
+	}
+
+	return fileStrings{
+		data,
+		size,
+		modTime,
+	}
+}
+
+func (f fileStrings) Size() int64 {
+	return f.size
+}
+
+func (fileStrings) Mode() fs.FileMode {
+	return ModeFile
+}
+
+func (f fileStrings) ModTime() time.Time {
+	return f.modTime
+}
+
+func (f fileStrings) Open() (File, error) {
+	return &fileStringsOpen{data: f.data, size: f.size}, nil
+}
+
+type fileStringsOpen struct {
+	data      []string
+	size, pos int64
+}
+
+func (f *fileStringsOpen) Read(p []byte) (int, error) {
+	if f.pos >= f.size {
+		return 0, io.EOF
+	}
+
+	var (
+		n      int
+		offset = f.pos
+	)
+
+	for _, s := range f.data {
+		if offset >= int64(len(s)) {
+			offset -= int64(len(s))
+
+			continue
+		}
+
+		n += copy(p[n:], s[offset:])
+		offset = 0
+
+		if n == len(p) {
+			break
+		}
+	}
+
+	f.pos += int64(n)
+
+	return n, nil
+}
+
+func (f *fileStringsOpen) Seek(offset int64, whence int) (int64, error) {
+	pos := f.pos
+
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos += offset
+	case io.SeekEnd:
+		pos = f.size + offset
+	default:
+		return 0, fs.ErrInvalid
+	}
+
+	if pos < 0 {
+		return 0, fs.ErrInvalid
+	}
+
+	f.pos = pos
+
+	return pos, nil
+}
+
+func (*fileStringsOpen) Readdir(int) ([]fs.FileInfo, error) {
+	return nil, fs.ErrInvalid
+}
+
+func (*fileStringsOpen) Close() error {
+	return nil
+}
+
 // OSFile is the path of a file in the real filesystem to be put into the
 // in-memory filesystem.
 type OSFile string
@@ -1,6 +1,9 @@
 package httpdir
 
 import (
+	"errors"
+	"io"
+	"io/fs"
 	"testing"
 	"time"
 )
@@ -124,3 +127,81 @@ func TestString(t *testing.T) {
 		t.Errorf("expecting to have read \"!\", read %s", tr)
 	}
 }
+
+func TestStrings(t *testing.T) {
+	mt := time.Now()
+	s := FileStrings([]string{"Hello", ", ", "", "World", "!"}, mt)
+
+	if !s.ModTime().Equal(mt) {
+		t.Errorf("expecting time %v, got %v", mt, s.ModTime())
+	}
+
+	if s.Mode() != ModeFile {
+		t.Errorf("expecting mode %v, got %v", ModeFile, s.Mode())
+	}
+
+	if s.Size() != 13 {
+		t.Errorf("expecting size 13, got %d", s.Size())
+	}
+
+	f, err := s.Open()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tr := make([]byte, 6)
+
+	for n, test := range [...]string{"Hello,", " World", "!"} {
+		m, err := f.Read(tr)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", n+1, err)
+		} else if string(tr[:m]) != test {
+			t.Errorf("test %d: expecting to have read %q, read %q", n+1, test, tr[:m])
+		}
+	}
+
+	if _, err := f.Read(tr); err != io.EOF {
+		t.Errorf("expecting EOF, got %v", err)
+	}
+
+	for n, test := range [...]struct {
+		Offset int64
+		Whence int
+		Pos    int64
+		Next   string
+	}{
+		{Offset: 4, Whence: io.SeekStart, Pos: 4, Next: "o, Wor"},
+		{Offset: -8, Whence: io.SeekCurrent, Pos: 2, Next: "llo, W"},
+		{Offset: -3, Whence: io.SeekEnd, Pos: 10, Next: "ld!"},
+		{Offset: 20, Whence: io.SeekStart, Pos: 20, Next: ""},
+	} {
+		pos, err := f.Seek(test.Offset, test.Whence)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", n+1, err)
+
+			continue
+		} else if pos != test.Pos {
+			t.Errorf("test %d: expecting position %d, got %d", n+1, test.Pos, pos)
+		}
+
+		m, _ := f.Read(tr)
+		if string(tr[:m]) != test.Next {
+			t.Errorf("test %d: expecting to read %q, read %q", n+1, test.Next, tr[:m])
+		}
+	}
+
+	if _, err := f.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expecting invalid error, got %v", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all, err := io.ReadAll(f)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if string(all) != "Hello, World!" {
+		t.Errorf("expecting to read %q, read %q", "Hello, World!", all)
+	}
+}
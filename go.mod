@@ -3,7 +3,10 @@ module vimagination.zapto.org/httpdir
 go 1.18
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/foobaz/go-zopfli v0.0.0-20140122214029-7432051485e2
 	github.com/google/brotli/go/cbrotli v0.0.0-20220110100810-f4153a09f87c
+	github.com/klauspost/compress v1.17.2
+	golang.org/x/crypto v0.24.0
 	vimagination.zapto.org/memio v1.0.0
 )
@@ -0,0 +1,71 @@
+package httpdir
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Errors returned when a name is rejected. Each of these will also match
+// fs.ErrInvalid when using errors.Is.
+var (
+	ErrNULByte      = fmt.Errorf("name contains NUL byte: %w", fs.ErrInvalid)
+	ErrParentDir    = fmt.Errorf("name contains parent directory reference: %w", fs.ErrInvalid)
+	ErrEmptyName    = fmt.Errorf("name has empty final component: %w", fs.ErrInvalid)
+	ErrNotDirectory = fmt.Errorf("not a directory, cannot descend: %w", fs.ErrInvalid)
+)
+
+// splitPath validates the given name, returning its components.
+//
+// A leading slash is optional, and empty and '.' components are ignored. Names
+// containing NUL bytes or '..' components are rejected. When file is true,
+// the name must have a non-empty final component, and so cannot refer to the
+// root directory.
+//
+// The Path of a returned error is the name up to, and including, the rejected
+// component, with a leading slash, as with the errors from pathError.
+func splitPath(op, name string, file bool) ([]string, error) {
+	var parts []string
+
+	for _, part := range strings.Split(name, "/") {
+		if strings.IndexByte(part, 0) >= 0 {
+			return nil, pathError(op, append(parts, part), ErrNULByte)
+		}
+
+		switch part {
+		case "", ".":
+		case "..":
+			return nil, pathError(op, append(parts, part), ErrParentDir)
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	if file {
+		if last := name[strings.LastIndexByte(name, '/')+1:]; last == "" || last == "." || len(parts) == 0 {
+			return nil, pathError(op, append(parts, last), ErrEmptyName)
+		}
+	}
+
+	return parts, nil
+}
+
+// pathError returns an *fs.PathError with the Path formed from the given
+// components, with a leading slash.
+func pathError(op string, parts []string, err error) error {
+	return &fs.PathError{Op: op, Path: "/" + strings.Join(parts, "/"), Err: err}
+}
+
+// notExist converts an error caused by an invalid name, or by descending
+// through a file, to one matching fs.ErrNotExist, as http.FileServer would
+// otherwise respond with a 500 Internal Server Error.
+func notExist(err error) error {
+	var pe *fs.PathError
+
+	if errors.Is(err, fs.ErrInvalid) && errors.As(err, &pe) {
+		return &fs.PathError{Op: pe.Op, Path: pe.Path, Err: fs.ErrNotExist}
+	}
+
+	return err
+}
@@ -0,0 +1,77 @@
+package httpdir
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestSplitPath(t *testing.T) {
+	for n, test := range [...]struct {
+		Name  string
+		File  bool
+		Parts []string
+		Err   error
+	}{
+		{Name: "", Parts: nil},
+		{Name: "/", Parts: nil},
+		{Name: "a", Parts: []string{"a"}},
+		{Name: "/a/b", Parts: []string{"a", "b"}},
+		{Name: "a//b", Parts: []string{"a", "b"}},
+		{Name: "./a/./b/", Parts: []string{"a", "b"}},
+		{Name: "a/../b", Err: ErrParentDir},
+		{Name: "..", Err: ErrParentDir},
+		{Name: "a\x00b", Err: ErrNULByte},
+		{Name: "/a/b", File: true, Parts: []string{"a", "b"}},
+		{Name: "a//b", File: true, Parts: []string{"a", "b"}},
+		{Name: "/", File: true, Err: ErrEmptyName},
+		{Name: "", File: true, Err: ErrEmptyName},
+		{Name: "a/b/", File: true, Err: ErrEmptyName},
+		{Name: "a/.", File: true, Err: ErrEmptyName},
+	} {
+		parts, err := splitPath("test", test.Name, test.File)
+		if !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		} else if err != nil && !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("test %d: expecting error to match fs.ErrInvalid", n+1)
+		} else if strings.Join(parts, "/") != strings.Join(test.Parts, "/") || len(parts) != len(test.Parts) {
+			t.Errorf("test %d: expecting parts %q, got %q", n+1, test.Parts, parts)
+		}
+	}
+}
+
+func FuzzSplitPath(f *testing.F) {
+	for _, seed := range [...]string{"", "/", "a/b", "/a//b/", "a/../b", "./a", "a\x00b", "a/."} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, name string, file bool) {
+		parts, err := splitPath("fuzz", name, file)
+		if err != nil {
+			if !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("expecting error to match fs.ErrInvalid, got %v", err)
+			}
+
+			return
+		}
+
+		if file && len(parts) == 0 {
+			t.Fatalf("expecting non-empty parts for file name %q", name)
+		}
+
+		for _, part := range parts {
+			if part == "" || part == "." || part == ".." || strings.ContainsAny(part, "/\x00") {
+				t.Fatalf("invalid component %q from name %q", part, name)
+			}
+		}
+
+		again, err := splitPath("fuzz", "/"+strings.Join(parts, "/"), file)
+		if err != nil {
+			t.Fatalf("unexpected error re-splitting %q: %s", name, err)
+		} else if strings.Join(again, "/") != strings.Join(parts, "/") {
+			t.Fatalf("expecting re-split of %q to give %q, got %q", name, parts, again)
+		}
+	})
+}
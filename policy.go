@@ -0,0 +1,101 @@
+package httpdir
+
+import (
+	"io/fs"
+	"path"
+)
+
+// Policy determines how a node, matched by a Rule, is treated.
+type Policy uint8
+
+// Policy values.
+const (
+	// PolicyAllow lists and serves matched nodes as normal.
+	PolicyAllow Policy = iota
+	// PolicyHide removes matched nodes from directory listings, but allows
+	// them to be opened.
+	PolicyHide
+	// PolicyDeny removes matched nodes from directory listings and returns
+	// fs.ErrPermission when attempting to open them.
+	PolicyDeny
+	// PolicyNotFound removes matched nodes from directory listings and
+	// returns fs.ErrNotExist when attempting to open them.
+	PolicyNotFound
+)
+
+// Rule matches the names of nodes against a glob Pattern, as understood by
+// path.Match, and determines the Policy that applies to those nodes.
+type Rule struct {
+	Pattern string
+	Policy  Policy
+}
+
+// HideDotfiles is an Option that causes all nodes whose name begins with a
+// '.' to be removed from directory listings and to be reported as not
+// existing.
+//
+// This can be overridden for a directory, and its descendants, by using
+// SetRules with a PolicyAllow Rule.
+func HideDotfiles(d *Dir) {
+	d.d.rules = []Rule{{Pattern: ".*", Policy: PolicyNotFound}}
+}
+
+// SetRules is a convenience function for Default.SetRules.
+func SetRules(name string, rules ...Rule) error {
+	return Default.SetRules(name, rules...)
+}
+
+// SetRules replaces the rules of the named directory.
+//
+// The rules of a directory apply to its children and all of its descendants.
+// When determining the Policy of a node, the rules of its parent are checked
+// in order, followed by those of each ancestor in turn, with the first
+// matching Rule being used. Nodes that match no Rule are allowed.
+//
+// The rules of the root directory therefore apply to the entire Dir.
+func (d Dir) SetRules(name string, rules ...Rule) error {
+	for _, r := range rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return &fs.PathError{Op: "setrules", Path: r.Pattern, Err: err}
+		}
+	}
+
+	parts, err := splitPath("setrules", name, false)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := d.getPath("setrules", parts)
+	if err != nil {
+		return err
+	}
+
+	dn := dirs[len(dirs)-1]
+	dn.rules = append(rules[:0:0], rules...)
+
+	dn.invalidate()
+
+	return nil
+}
+
+func (d *dir) policy(name string) Policy {
+	for dn := d; dn != nil; dn = dn.parent {
+		for _, r := range dn.rules {
+			if ok, _ := path.Match(r.Pattern, name); ok {
+				return r.Policy
+			}
+		}
+	}
+
+	return PolicyAllow
+}
+
+func (d *dir) invalidate() {
+	d.resetListing()
+
+	for _, n := range d.contents {
+		if nd, ok := n.(*dir); ok {
+			nd.invalidate()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package httpdir
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	d := New(time.Now(), HideDotfiles)
+
+	for _, name := range [...]string{"/.env", "/public/.well-known/file", "/public/app.js", "/public/app.js.map", "/public/.swp", "/internal/secret", "/internal/readme"} {
+		if err := d.Create(name, FileString(name, time.Now())); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	d.d.index = true
+	d.d.contents["public"].(*dir).index = true
+	d.d.contents["internal"].(*dir).index = true
+
+	if err := d.SetRules("/public", Rule{Pattern: ".well-known", Policy: PolicyAllow}, Rule{Pattern: "*.map", Policy: PolicyHide}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.SetRules("/internal", Rule{Pattern: "secret", Policy: PolicyDeny}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.SetRules("/", Rule{Pattern: "[", Policy: PolicyDeny}); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("expecting bad pattern error, got %v", err)
+	}
+
+	for n, test := range [...]struct {
+		Name string
+		Err  error
+	}{
+		{Name: "/.env", Err: fs.ErrNotExist},
+		{Name: "/public/.swp", Err: fs.ErrNotExist},
+		{Name: "/public/.well-known/file"},
+		{Name: "/public/app.js"},
+		{Name: "/public/app.js.map"},
+		{Name: "/internal/secret", Err: fs.ErrPermission},
+		{Name: "/internal/readme"},
+	} {
+		if _, err := d.Open(test.Name); !errors.Is(err, test.Err) {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.Err, err)
+		}
+	}
+
+	for n, test := range [...]struct {
+		Dir   string
+		Names []string
+	}{
+		{Dir: "/", Names: []string{"internal", "public"}},
+		{Dir: "/public", Names: []string{".well-known", "app.js"}},
+		{Dir: "/internal", Names: []string{"readme"}},
+	} {
+		f, err := d.Open(test.Dir)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		}
+
+		fis, err := f.Readdir(-1)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", n+1, err)
+		} else if len(fis) != len(test.Names) {
+			t.Errorf("test %d: expecting %d entries, got %d", n+1, len(test.Names), len(fis))
+
+			continue
+		}
+
+		for m, fi := range fis {
+			if fi.Name() != test.Names[m] {
+				t.Errorf("test %d: expecting entry %d to be %q, got %q", n+1, m+1, test.Names[m], fi.Name())
+			}
+		}
+	}
+
+	if err := d.SetRules("/"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := d.Open("/.env"); err != nil {
+		t.Errorf("unexpected error after clearing rules: %s", err)
+	}
+
+	if f, err := d.Open("/"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if fis, _ := f.Readdir(-1); len(fis) != 3 {
+		t.Errorf("expecting 3 entries after clearing rules, got %d", len(fis))
+	}
+}